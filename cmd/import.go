@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"wb-tech-backend/internal/cachesync"
+	"wb-tech-backend/internal/importer"
+	"wb-tech-backend/internal/repository"
+	"wb-tech-backend/internal/service"
+)
+
+// runImport imports historical orders from JSON or NDJSON files, optionally gzipped:
+//
+//	import [-workers n] [-batch n] [-state file] [-rejects file] path...
+//
+// Interrupted import is resumed by running it again with the same state file.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	workers := fs.Int("workers", 4, "number of batches saved in parallel")
+	batch := fs.Int("batch", 100, "number of orders saved in one transaction")
+	state := fs.String("state", "import.state", "file listing imported files")
+	rejects := fs.String("rejects", "import.rejects.ndjson", "file receiving orders that can't be imported")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatalf("Usage: import [flags] path...")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg := loadConfig()
+	repo, err := repository.NewRepository(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Init repository: %s", err)
+	}
+	defer func() {
+		if err := repo.Close(); err != nil {
+			slog.Debug("Error with close repository", "error", err)
+		}
+	}()
+
+	// replicas of service are notified of updated orders like of consumed ones
+	serv := service.NewService(repo, cfg)
+	if cfg.CacheSync.Enabled {
+		broadcaster, err := cachesync.New(serv, cfg.CacheSync, "test-cluster", cfg.Nats.SubUrl)
+		if err != nil {
+			log.Fatalf("Init cache sync: %s", err)
+		}
+		defer func() {
+			if err := broadcaster.Close(); err != nil {
+				slog.Debug("Error with close cache sync", "error", err)
+			}
+		}()
+		serv.CacheNotifier = broadcaster
+	}
+
+	im := importer.NewImporter(serv, importer.Config{
+		BatchSize:  *batch,
+		Workers:    *workers,
+		StateFile:  *state,
+		RejectFile: *rejects,
+	})
+	summary, err := im.Import(ctx, fs.Args())
+	log.Printf("Imported %d files: %d orders read, %d stored, %d already stored, %d rejected to %s",
+		summary.Files, summary.Read, summary.Stored, summary.Skipped, summary.Rejected, *rejects)
+	if err != nil {
+		log.Fatalf("Import interrupted: %s, run it again to resume", err)
+	}
+}
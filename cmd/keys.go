@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/pkg/keyring"
+	"wb-tech-backend/internal/repository"
+)
+
+// runKeys manages keyring of delivery personal data encryption:
+//
+//	keys add [-keyring path]  adds new key to keyring and makes it active
+//	keys rotate [-batch n]    re-encrypts deliveries with the active key
+//	keys decrypt [-batch n]   stores deliveries in plain text before encryption migration is rolled back
+func runKeys(args []string) {
+	if len(args) == 0 {
+		log.Fatalf("Usage: keys add|rotate|decrypt [flags]")
+	}
+	cfg := loadConfig()
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("keys add", flag.ExitOnError)
+		path := fs.String("keyring", cfg.Storage.KeyringFile, "path to keyring file")
+		_ = fs.Parse(args[1:])
+		if *path == "" {
+			log.Fatalf("Keyring file is not set")
+		}
+		id, err := keyring.AddKey(*path)
+		if err != nil {
+			log.Fatalf("Add key: %s", err)
+		}
+		log.Printf("Key %s added to %s and made active, run \"keys rotate\" after restarting the service", id, *path)
+	case "rotate":
+		total := rewriteDeliveries(cfg, "rotate", args[1:], (*repository.Repository).ReencryptDeliveries)
+		log.Printf("Done, %d deliveries re-encrypted", total)
+	case "decrypt":
+		total := rewriteDeliveries(cfg, "decrypt", args[1:], (*repository.Repository).DecryptDeliveries)
+		log.Printf("Done, %d deliveries decrypted, encryption migration can be rolled back", total)
+	default:
+		log.Fatalf("Unknown keys command %q, expected one of: add, rotate, decrypt", args[0])
+	}
+}
+
+// rewriteDeliveries calls rewrite batch by batch until no deliveries are left and returns their number.
+func rewriteDeliveries(cfg *core.Config, command string, args []string,
+	rewrite func(*repository.Repository, context.Context, uint64) (int, error)) int {
+	fs := flag.NewFlagSet("keys "+command, flag.ExitOnError)
+	batch := fs.Uint64("batch", 500, "number of deliveries rewritten in one transaction")
+	_ = fs.Parse(args)
+	ctx := context.Background()
+	repo, err := repository.NewRepository(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Init repository: %s", err)
+	}
+	if repo.Keyring == nil {
+		log.Fatalf("Keyring file is not set")
+	}
+	var total int
+	for {
+		n, err := rewrite(repo, ctx, *batch)
+		if err != nil {
+			log.Fatalf("Rewrite deliveries: %s", err)
+		}
+		if n == 0 {
+			return total
+		}
+		total += n
+		log.Printf("%d deliveries processed", total)
+	}
+}
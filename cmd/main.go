@@ -4,14 +4,24 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
+	"wb-tech-backend/internal/cachesync"
+	"wb-tech-backend/internal/consumer"
 	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/grpc_server"
 	"wb-tech-backend/internal/http_server"
+	"wb-tech-backend/internal/kafka"
 	"wb-tech-backend/internal/nats"
+	"wb-tech-backend/internal/outbox"
 	"wb-tech-backend/internal/pkg/config"
 	"wb-tech-backend/internal/repository"
 	"wb-tech-backend/internal/service"
@@ -21,57 +31,224 @@ import (
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/jackc/pgx/v4/stdlib"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
-	ctx := context.Background()
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "serve":
+		if err := serve(); err != nil {
+			log.Fatalf(err.Error())
+		}
+	case "keys":
+		runKeys(args)
+	case "import":
+		runImport(args)
+	default:
+		log.Fatalf("Unknown command %q, expected one of: serve, keys, import", command)
+	}
+}
+
+func loadConfig() *core.Config {
 	loader := config.PrepareLoader(config.WithConfigPath("./config.yml"))
 
 	cfg, err := core.ParseConfig(loader)
 	if err != nil {
 		log.Fatalf("Failed to parse config: %s", err)
 	}
+	return cfg
+}
 
-	err = retry.Do(func() error {
+// serve runs service until it gets interrupt or terminate signal or one of its servers fails.
+func serve() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	cfg := loadConfig()
+
+	err := retry.Do(func() error {
 		return UpMigrations(cfg)
 	}, retry.Attempts(4), retry.Delay(2*time.Second))
 	if err != nil {
-		log.Fatalf(err.Error())
+		return err
 	}
 
 	repo, err := repository.NewRepository(ctx, cfg)
 	if err != nil {
-		log.Fatalf("Init repository: %s", err)
+		return fmt.Errorf("init repository: %w", err)
 	}
+	defer func() {
+		if err := repo.Close(); err != nil {
+			slog.Debug("Error with close repository", "error", err)
+		}
+	}()
 
 	serv := service.NewService(repo, cfg)
 
-	n, err := nats.NewNats(serv, "test-cluster", cfg.Nats.Sub, cfg.Nats.SubUrl)
+	var wg sync.WaitGroup
+	if cfg.Cache.ListenChanges {
+		listener := cachesync.NewListener(serv, cfg.Storage.URL)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := listener.Run(ctx); err != nil {
+				slog.Debug("Error with order changes listener", "error", err)
+			}
+		}()
+	}
+	if cfg.CacheSync.Enabled {
+		broadcaster, err := cachesync.New(serv, cfg.CacheSync, "test-cluster", cfg.Nats.SubUrl)
+		if err != nil {
+			return fmt.Errorf("init cache sync: %w", err)
+		}
+		defer func() {
+			if err := broadcaster.Close(); err != nil {
+				slog.Debug("Error with close cache sync", "error", err)
+			}
+		}()
+		serv.CacheNotifier = broadcaster
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := broadcaster.Run(ctx); err != nil {
+				slog.Debug("Error with cache sync", "error", err)
+			}
+		}()
+	}
+
+	c, err := NewConsumer(cfg, serv)
 	if err != nil {
-		log.Fatalf("Init nats: %s", err)
+		return fmt.Errorf("init consumer: %w", err)
 	}
 	defer func() {
-		err := n.NatsConnection.Close()
-		slog.Debug("Error with close nats: %s", err)
-		return
+		if err := c.Close(); err != nil {
+			slog.Debug("Error with close consumer", "error", err)
+		}
 	}()
 
-	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		err := n.SubscribeToUpdates(&wg, ctx, cfg.Nats.Subject)
+		defer wg.Done()
+		if err := c.Run(ctx); err != nil {
+			slog.Debug("Error with consumer", "error", err)
+		}
+	}()
+
+	if cfg.Outbox.Enabled {
+		pub, err := nats.NewPublisher("test-cluster", cfg.Outbox.ClientId, cfg.Nats.SubUrl)
+		if err != nil {
+			return fmt.Errorf("init outbox publisher: %w", err)
+		}
+		defer func() {
+			if err := pub.Close(); err != nil {
+				slog.Debug("Error with close outbox publisher", "error", err)
+			}
+		}()
+		relay := outbox.NewRelay(repo, pub, cfg.Outbox)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := relay.Run(ctx); err != nil {
+				slog.Debug("Error with outbox relay", "error", err)
+			}
+		}()
+	}
+
+	app, err := http_server.New(serv)
+	if err != nil {
+		return fmt.Errorf("init http server: %w", err)
+	}
+
+	// orders missing from cache are read from storage, so requests are served during warm-up,
+	// but readiness probe reports not ready until it is finished
+	app.Server.SetReady(false)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// service stays not ready until warm-up succeeds
+		err := retry.Do(func() error {
+			return serv.WarmUpCache(ctx)
+		},
+			retry.Context(ctx),
+			retry.Attempts(0),
+			retry.Delay(time.Second),
+			retry.MaxDelay(time.Minute),
+			retry.OnRetry(func(n uint, err error) {
+				slog.Error("Error with warm up cache", "attempt", n+1, "error", err)
+			}))
 		if err != nil {
-			slog.Debug("Error with nats: %s", err)
+			return
+		}
+		app.Server.SetReady(true)
+		if err := serv.RunCacheSnapshots(ctx); err != nil {
+			slog.Debug("Error with cache snapshots", "error", err)
 		}
 	}()
 
-	app := http_server.New(serv)
+	var grpcServer *grpc_server.Server
+	if cfg.GRPC.Enabled {
+		grpcServer, err = grpc_server.New(serv)
+		if err != nil {
+			return fmt.Errorf("init grpc server: %w", err)
+		}
+	}
 
-	if err := app.Start(ctx); err != nil {
-		log.Fatalf(err.Error())
+	// servers are shut down on signal or when one of them fails, shutdown is not cancelled with ctx,
+	// so running requests are drained
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		if err := app.Start(gctx); !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("run http server: %w", err)
+		}
+		return nil
+	})
+	if grpcServer != nil {
+		g.Go(func() error {
+			if err := grpcServer.Run(gctx); err != nil {
+				return fmt.Errorf("run grpc server: %w", err)
+			}
+			return nil
+		})
 	}
+	g.Go(func() error {
+		<-gctx.Done()
+		slog.Info("Shutting down servers")
+		shutdownCtx := context.WithoutCancel(gctx)
+		if err := app.Server.Shutdown(shutdownCtx); err != nil {
+			slog.Debug("Error with shutdown http server", "error", err)
+		}
+		if grpcServer != nil {
+			if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+				slog.Debug("Error with shutdown grpc server", "error", err)
+			}
+		}
+		return nil
+	})
+	err = g.Wait()
+	// background workers are stopped when servers fail as well
+	stop()
 	wg.Wait()
+	return err
 }
+
+func NewConsumer(cfg *core.Config, saver consumer.OrderSaver) (consumer.Consumer, error) {
+	switch cfg.Consumer.Type {
+	case consumer.TypeNats, "":
+		n, err := nats.NewNats(saver, "test-cluster", cfg.Nats.Sub, cfg.Nats.SubUrl, cfg.Nats.Subject)
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	case consumer.TypeKafka:
+		return kafka.NewKafka(saver, cfg.Kafka.Brokers, cfg.Kafka.GroupId, cfg.Kafka.Topic), nil
+	default:
+		return nil, fmt.Errorf("unknown consumer type %q", cfg.Consumer.Type)
+	}
+}
+
 func UpMigrations(cfg *core.Config) error {
 	db, err := sql.Open("pgx", cfg.Storage.URL)
 	if err != nil {
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"wb-tech-backend/internal/models"
+)
+
+var (
+	firstNames = []string{"Ivan", "Anna", "Petr", "Maria", "John", "Olga", "Sergey", "Elena"}
+	lastNames  = []string{"Ivanov", "Petrova", "Smith", "Sidorov", "Kuznetsova", "Popov"}
+	cities     = []struct{ city, region, phoneCode string }{
+		{"Moscow", "Moscow", "+7"},
+		{"Saint Petersburg", "Leningrad Oblast", "+7"},
+		{"Kazan", "Tatarstan", "+7"},
+		{"Minsk", "Minsk Region", "+375"},
+		{"Kiryat Mozkin", "Kraiot", "+972"},
+	}
+	streets    = []string{"Lenina", "Pushkina", "Ploshad Mira", "Sadovaya", "Tverskaya"}
+	currencies = []string{"RUB", "USD", "EUR"}
+	providers  = []string{"wbpay", "sbp", "card"}
+	banks      = []string{"alpha", "sber", "tinkoff", "vtb"}
+	services   = []string{"meest", "cdek", "boxberry", "wb"}
+	products   = []struct{ name, brand string }{
+		{"Mascaras", "Vivienne Sabo"},
+		{"Lipstick", "Maybelline"},
+		{"Sneakers", "Nike"},
+		{"T-shirt", "Adidas"},
+		{"Headphones", "Sony"},
+		{"Phone case", "Samsung"},
+		{"Backpack", "Xiaomi"},
+	}
+	sizes = []string{"0", "S", "M", "L", "XL", "42"}
+)
+
+// generator makes random orders satisfying validation rules of the service with consistent totals.
+type generator struct {
+	rnd    *rand.Rand
+	prefix string
+	n      int
+}
+
+func newGenerator(seed int64) *generator {
+	return &generator{
+		rnd:    rand.New(rand.NewSource(seed)),
+		prefix: strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+func (g *generator) Order() models.Order {
+	g.n++
+	uid := fmt.Sprintf("%s%08dgen", g.prefix, g.n)
+	track := "WB" + strings.ToUpper(g.hex(12))
+	city := cities[g.rnd.Intn(len(cities))]
+	first, last := pick(g.rnd, firstNames), pick(g.rnd, lastNames)
+	created := time.Now().UTC().Add(-time.Duration(g.rnd.Intn(30*24)) * time.Hour).Truncate(time.Second)
+
+	items := make([]models.Item, 1+g.rnd.Intn(4))
+	goodsTotal := 0
+	for i := range items {
+		product := products[g.rnd.Intn(len(products))]
+		price := 100 + g.rnd.Intn(10000)
+		sale := 1 + g.rnd.Intn(70)
+		items[i] = models.Item{
+			ChrtId:      1000000 + g.rnd.Intn(9000000),
+			TrackNumber: track,
+			Price:       price,
+			RId:         g.hex(20),
+			Name:        product.name,
+			Sale:        sale,
+			Size:        pick(g.rnd, sizes),
+			TotalPrice:  price * (100 - sale) / 100,
+			NmId:        1000000 + g.rnd.Intn(9000000),
+			Brand:       product.brand,
+			Status:      202,
+		}
+		goodsTotal += items[i].TotalPrice
+	}
+	deliveryCost := 100 + g.rnd.Intn(2000)
+	customFee := 0
+	if g.rnd.Intn(5) == 0 {
+		customFee = g.rnd.Intn(500)
+	}
+
+	return models.Order{
+		OrderId:     uid,
+		TrackNumber: track,
+		Entry:       "WBIL",
+		Delivery: models.Delivery{
+			Name:    first + " " + last,
+			Phone:   city.phoneCode + strconv.Itoa(900000000+g.rnd.Intn(99999999)),
+			Zip:     strconv.Itoa(100000 + g.rnd.Intn(900000)),
+			City:    city.city,
+			Address: fmt.Sprintf("%s %d", pick(g.rnd, streets), 1+g.rnd.Intn(150)),
+			Region:  city.region,
+			Email:   strings.ToLower(first+"."+last) + strconv.Itoa(g.rnd.Intn(1000)) + "@example.com",
+		},
+		Payment: models.Payment{
+			Transaction:  uid,
+			Currency:     pick(g.rnd, currencies),
+			Provider:     pick(g.rnd, providers),
+			Amount:       goodsTotal + deliveryCost + customFee,
+			PaymentDt:    created.Unix(),
+			Bank:         pick(g.rnd, banks),
+			DeliveryCost: deliveryCost,
+			GoodsTotal:   goodsTotal,
+			CustomFee:    customFee,
+		},
+		Items:           items,
+		Locale:          pick(g.rnd, []string{"en", "ru"}),
+		CustomerId:      "customer" + strconv.Itoa(g.rnd.Intn(1000)),
+		DeliveryService: pick(g.rnd, services),
+		Shardkey:        strconv.Itoa(g.rnd.Intn(10)),
+		SmId:            1 + g.rnd.Intn(100),
+		DateCreated:     created,
+		OofShard:        strconv.Itoa(1 + g.rnd.Intn(2)),
+	}
+}
+
+// Invalid returns message the service must reject: malformed JSON or order failing validation.
+func (g *generator) Invalid() []byte {
+	order := g.Order()
+	switch g.rnd.Intn(4) {
+	case 0:
+		return []byte(`{"order_uid": "` + order.OrderId + `", "items": [`)
+	case 1:
+		order.TrackNumber = ""
+	case 2:
+		order.Delivery.Email = "not an email"
+	default:
+		order.Items[0].Status = 0
+	}
+	data, _ := json.Marshal(order)
+	return data
+}
+
+func (g *generator) hex(n int) string {
+	const digits = "0123456789abcdef"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = digits[g.rnd.Intn(len(digits))]
+	}
+	return string(b)
+}
+
+func pick(rnd *rand.Rand, values []string) string {
+	return values[rnd.Intn(len(values))]
+}
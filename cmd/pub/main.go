@@ -1,53 +1,313 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"math/rand"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
 
 	"wb-tech-backend/internal/core"
 	"wb-tech-backend/internal/pkg/config"
 
-	"github.com/nats-io/stan.go"
+	"golang.org/x/time/rate"
 )
 
+const usage = `Usage: pub <command> [flags]
+
+Commands:
+  publish [-broker b] [-rate r] [-verify url] path|glob|- ...
+        publish files, each JSON value or array element is a message; - reads stdin
+  generate [-n n] [-seed s]
+        write n random valid orders to stdout as NDJSON
+  load [-broker b] [-rate r] [-duration d] [-n n] [-invalid ratio] [-concurrency c]
+        publish random orders at rate r for duration d or until n are published
+
+With -verify url published orders are polled by GET /order until they are stored or -verify-timeout
+expires and compared with the sent ones.`
+
+// message is a message to publish. OrderId is empty for messages that are not valid orders.
+type message struct {
+	OrderId string
+	Data    []byte
+}
+
 func main() {
-	loader := config.PrepareLoader(config.WithConfigPath("config.yml"))
-	cfg, err := core.ParseConfig(loader)
-	if err != nil {
-		log.Fatalf("Error with read config: %s", err)
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	command, args := os.Args[1], os.Args[2:]
+	var err error
+	switch command {
+	case "publish":
+		err = runPublish(ctx, args)
+	case "generate":
+		err = runGenerate(args)
+	case "load":
+		err = runLoad(ctx, args)
+	default:
+		log.Fatal(usage)
 	}
-	sc, err := stan.Connect("test-cluster", cfg.Nats.Prod, stan.NatsURL(cfg.Nats.PubUrl))
 	if err != nil {
-		log.Fatalf("Error with nats connection: %s", err)
+		log.Fatalf("Error with %s: %s", command, err)
 	}
-	defer func(sc stan.Conn) {
-		err := sc.Close()
-		if err != nil {
-			log.Fatalf("Error with close nats connection: %s", err)
+}
+
+func loadConfig() (*core.Config, error) {
+	loader := config.PrepareLoader(config.WithConfigPath("config.yml"))
+	return core.ParseConfig(loader)
+}
+
+// publishFlags are flags shared by commands publishing messages.
+type publishFlags struct {
+	broker        *string
+	rate          *float64
+	concurrency   *int
+	verify        *string
+	verifyTimeout *time.Duration
+	apiKey        *string
+}
+
+func addPublishFlags(fs *flag.FlagSet) publishFlags {
+	return publishFlags{
+		broker:        fs.String("broker", "", "nats or kafka, consumer.type of config by default"),
+		rate:          fs.Float64("rate", 0, "messages per second, unlimited if 0"),
+		concurrency:   fs.Int("concurrency", 8, "number of messages published at once"),
+		verify:        fs.String("verify", "", "base URL of the service, e.g. http://localhost:8080, to check published orders are stored"),
+		verifyTimeout: fs.Duration("verify-timeout", 30*time.Second, "how long to wait for published order to be stored"),
+		apiKey:        fs.String("api-key", "", "API key for the service when verifying, it needs admin role to compare personal data"),
+	}
+}
+
+func runPublish(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	pf := addPublishFlags(fs)
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		return errors.New("no files to publish")
+	}
+	messages := make(chan message)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(messages)
+		errs <- readMessages(ctx, fs.Args(), messages)
+	}()
+	if err := publishAndVerify(ctx, pf, messages); err != nil {
+		return err
+	}
+	return <-errs
+}
+
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	n := fs.Int("n", 10, "number of orders")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed")
+	_ = fs.Parse(args)
+
+	g := newGenerator(*seed)
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+	for i := 0; i < *n; i++ {
+		if err := enc.Encode(g.Order()); err != nil {
+			return err
 		}
-	}(sc)
+	}
+	return w.Flush()
+}
+
+func runLoad(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("load", flag.ExitOnError)
+	pf := addPublishFlags(fs)
+	duration := fs.Duration("duration", time.Minute, "how long to publish")
+	n := fs.Int("n", 0, "maximum number of messages, unlimited if 0")
+	invalid := fs.Float64("invalid", 0, "ratio of invalid messages in [0, 1]")
+	seed := fs.Int64("seed", time.Now().UnixNano(), "random seed")
+	_ = fs.Parse(args)
+	if *invalid < 0 || *invalid > 1 {
+		return errors.New("invalid ratio must be in [0, 1]")
+	}
 
-	bytes, err := os.ReadFile("json_models/model.json")
+	genCtx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+	g := newGenerator(*seed)
+	rnd := rand.New(rand.NewSource(*seed))
+	messages := make(chan message)
+	go func() {
+		defer close(messages)
+		for i := 0; *n == 0 || i < *n; i++ {
+			var m message
+			if rnd.Float64() < *invalid {
+				m = message{Data: g.Invalid()}
+			} else {
+				order := g.Order()
+				data, _ := json.Marshal(order)
+				m = message{OrderId: order.OrderId, Data: data}
+			}
+			select {
+			case messages <- m:
+			case <-genCtx.Done():
+				return
+			}
+		}
+	}()
+	// verification outlives publishing duration, so it gets the parent context
+	return publishAndVerify(ctx, pf, messages)
+}
 
-	err = sc.Publish(cfg.Nats.Subject, bytes)
+// publishAndVerify publishes messages and, if requested, verifies that published orders are stored.
+func publishAndVerify(ctx context.Context, pf publishFlags, messages <-chan message) error {
+	var v *verifier
+	var onPublished func(message, time.Time)
+	if *pf.verify != "" {
+		v = newVerifier(*pf.verify, *pf.apiKey, *pf.verifyTimeout)
+		onPublished = func(m message, sentAt time.Time) {
+			v.Track(ctx, m, sentAt)
+		}
+	}
+	stats, err := publishAll(ctx, pf, messages, onPublished)
 	if err != nil {
-		log.Fatalf("Error with publish to nats: %s", err)
+		return err
 	}
-	log.Printf("first message successfuly publish to nats")
-
-	bytes, err = os.ReadFile("json_models/model2.json")
+	stats.Report(os.Stdout)
+	if v != nil {
+		v.Wait()
+		v.Report(os.Stdout)
+	}
+	return nil
+}
 
-	err = sc.Publish(cfg.Nats.Subject, bytes)
+// publishAll publishes messages at rate of pf until messages is closed or ctx is done.
+// onPublished, if set, is called for every successfully published message with time it was sent.
+func publishAll(ctx context.Context, pf publishFlags, messages <-chan message, onPublished func(message, time.Time)) (*publishStats, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	broker := *pf.broker
+	if broker == "" {
+		broker = cfg.Consumer.Type
+	}
+	pub, err := NewPublisher(cfg, broker)
 	if err != nil {
-		log.Fatalf("Error with publish to nats: %s", err)
+		return nil, err
+	}
+	defer func() {
+		if err := pub.Close(); err != nil {
+			log.Printf("Error with close publisher: %s", err)
+		}
+	}()
+
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if *pf.rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(*pf.rate), 1)
 	}
-	log.Printf("second message successfuly publish to nats")
+	stats := &publishStats{started: time.Now()}
+	sem := make(chan struct{}, max(*pf.concurrency, 1))
+	var wg sync.WaitGroup
+	for m := range messages {
+		if err = limiter.Wait(ctx); err != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(m message) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			start := time.Now()
+			err := pub.Publish(context.WithoutCancel(ctx), m.Data)
+			stats.mu.Lock()
+			defer stats.mu.Unlock()
+			if err != nil {
+				stats.failed++
+				log.Printf("Error with publish: %s", err)
+				return
+			}
+			stats.latencies.Add(time.Since(start))
+			stats.published++
+			if onPublished != nil {
+				onPublished(m, start)
+			}
+			if m.OrderId == "" {
+				stats.invalid++
+			}
+		}(m)
+	}
+	wg.Wait()
+	stats.finished = time.Now()
+	return stats, nil
+}
 
-	bytes, err = os.ReadFile("json_models/model3.json")
+// readMessages sends JSON values of files matching patterns to messages. Arrays are split into elements.
+func readMessages(ctx context.Context, patterns []string, messages chan<- message) error {
+	for _, pattern := range patterns {
+		if pattern == "-" {
+			if err := readValues(ctx, os.Stdin, messages); err != nil {
+				return fmt.Errorf("stdin: %w", err)
+			}
+			continue
+		}
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no files match %s", pattern)
+		}
+		for _, path := range paths {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			err = readValues(ctx, f, messages)
+			_ = f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
 
-	err = sc.Publish(cfg.Nats.Subject, bytes)
-	if err != nil {
-		log.Fatalf("Error with publish to nats: %s", err)
+func readValues(ctx context.Context, r io.Reader, messages chan<- message) error {
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		values := []json.RawMessage{raw}
+		if raw[0] == '[' {
+			if err := json.Unmarshal(raw, &values); err != nil {
+				return err
+			}
+		}
+		for _, v := range values {
+			var order struct {
+				OrderId string `json:"order_uid"`
+			}
+			_ = json.Unmarshal(v, &order)
+			select {
+			case messages <- message{OrderId: order.OrderId, Data: v}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
 	}
-	log.Printf("third message successfuly publish to nats")
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wb-tech-backend/internal/consumer"
+	"wb-tech-backend/internal/core"
+
+	"github.com/nats-io/stan.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher publishes messages to the broker the service consumes from.
+type Publisher interface {
+	Publish(ctx context.Context, data []byte) error
+	Close() error
+}
+
+func NewPublisher(cfg *core.Config, broker string) (Publisher, error) {
+	switch broker {
+	case consumer.TypeNats, "":
+		sc, err := stan.Connect(cfg.Nats.ClusterId, cfg.Nats.Prod, stan.NatsURL(cfg.Nats.PubUrl))
+		if err != nil {
+			return nil, fmt.Errorf("nats connection: %w", err)
+		}
+		return &natsPublisher{conn: sc, subject: cfg.Nats.Subject}, nil
+	case consumer.TypeKafka:
+		return &kafkaPublisher{writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Kafka.Brokers...),
+			Topic:        cfg.Kafka.Topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unknown broker %q", broker)
+	}
+}
+
+type natsPublisher struct {
+	conn    stan.Conn
+	subject string
+}
+
+// Publish waits for acknowledgement of nats streaming server.
+func (n *natsPublisher) Publish(_ context.Context, data []byte) error {
+	return n.conn.Publish(n.subject, data)
+}
+
+func (n *natsPublisher) Close() error {
+	return n.conn.Close()
+}
+
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func (k *kafkaPublisher) Publish(ctx context.Context, data []byte) error {
+	return k.writer.WriteMessages(ctx, kafka.Message{Value: data})
+}
+
+func (k *kafkaPublisher) Close() error {
+	return k.writer.Close()
+}
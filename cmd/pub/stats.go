@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencies collects durations and reports their percentiles.
+type latencies struct {
+	mu     sync.Mutex
+	values []time.Duration
+}
+
+func (l *latencies) Add(d time.Duration) {
+	l.mu.Lock()
+	l.values = append(l.values, d)
+	l.mu.Unlock()
+}
+
+func (l *latencies) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.values)
+}
+
+// Percentile returns p-th percentile, p is in [0, 100].
+func (l *latencies) Percentile(p float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.values) == 0 {
+		return 0
+	}
+	sort.Slice(l.values, func(i, j int) bool { return l.values[i] < l.values[j] })
+	i := int(float64(len(l.values)-1) * p / 100)
+	return l.values[i]
+}
+
+func (l *latencies) Report(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s latency: p50=%s p90=%s p99=%s max=%s\n", name,
+		l.Percentile(50), l.Percentile(90), l.Percentile(99), l.Percentile(100))
+}
+
+// publishStats is the result of publishing.
+type publishStats struct {
+	latencies latencies
+	mu        sync.Mutex
+	published int
+	invalid   int
+	failed    int
+	started   time.Time
+	finished  time.Time
+}
+
+func (s *publishStats) Report(w io.Writer) {
+	elapsed := s.finished.Sub(s.started)
+	fmt.Fprintf(w, "Published %d messages (%d invalid), %d failed in %s, %.1f msg/s\n",
+		s.published, s.invalid, s.failed, elapsed.Round(time.Millisecond), float64(s.published)/elapsed.Seconds())
+	s.latencies.Report(w, "Publish")
+}
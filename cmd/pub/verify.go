@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"wb-tech-backend/internal/models"
+)
+
+const (
+	minPollInterval = 50 * time.Millisecond
+	maxPollInterval = time.Second
+)
+
+// verifier polls GET /order of the service for published orders until they appear or timeout expires
+// and compares stored orders with the published ones.
+type verifier struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	timeout time.Duration
+
+	wg         sync.WaitGroup
+	latencies  latencies
+	mu         sync.Mutex
+	missing    []string
+	mismatched map[string]string
+}
+
+func newVerifier(baseURL, apiKey string, timeout time.Duration) *verifier {
+	return &verifier{
+		client:     &http.Client{Timeout: 5 * time.Second},
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		timeout:    timeout,
+		mismatched: make(map[string]string),
+	}
+}
+
+// Track starts verification of message published at sentAt. Messages which are not orders are ignored.
+func (v *verifier) Track(ctx context.Context, m message, sentAt time.Time) {
+	if m.OrderId == "" {
+		return
+	}
+	v.wg.Add(1)
+	go func() {
+		defer v.wg.Done()
+		v.verify(ctx, m, sentAt)
+	}()
+}
+
+// Wait waits for verification of all tracked messages.
+func (v *verifier) Wait() {
+	v.wg.Wait()
+}
+
+func (v *verifier) verify(ctx context.Context, m message, sentAt time.Time) {
+	ctx, cancel := context.WithDeadline(ctx, sentAt.Add(v.timeout))
+	defer cancel()
+	interval := minPollInterval
+	for {
+		body, found, err := v.getOrder(ctx, m.OrderId)
+		if err == nil && found {
+			v.latencies.Add(time.Since(sentAt))
+			if diff := compareOrders(m.Data, body); diff != "" {
+				v.mu.Lock()
+				v.mismatched[m.OrderId] = diff
+				v.mu.Unlock()
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			v.mu.Lock()
+			v.missing = append(v.missing, m.OrderId)
+			v.mu.Unlock()
+			return
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, maxPollInterval)
+	}
+}
+
+func (v *verifier) getOrder(ctx context.Context, orderId string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+"/order?order_uid="+url.QueryEscape(orderId), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if v.apiKey != "" {
+		req.Header.Set("X-API-Key", v.apiKey)
+	}
+	// lets support role compare personal data, admin role ignores it
+	req.Header.Set("X-Unmask-Reason", "publisher end-to-end verification")
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, true, nil
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+	}
+}
+
+// compareOrders returns description of the first difference of stored order from the sent one or empty
+// string if they are equal. Both are normalized through models.Order, so absent and zero fields are equal.
+func compareOrders(sent, stored []byte) string {
+	a, err := normalize(sent)
+	if err != nil {
+		return "sent order: " + err.Error()
+	}
+	b, err := normalize(stored)
+	if err != nil {
+		return "stored order: " + err.Error()
+	}
+	return diff("", a, b)
+}
+
+func normalize(data []byte) (interface{}, error) {
+	var order models.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(order)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	return v, json.Unmarshal(data, &v)
+}
+
+func diff(path string, a, b interface{}) string {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if d := diff(path+"."+k, av[k], bv[k]); d != "" {
+				return d
+			}
+		}
+		return ""
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			break
+		}
+		for i := range av {
+			if d := diff(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i]); d != "" {
+				return d
+			}
+		}
+		return ""
+	}
+	if reflect.DeepEqual(a, b) {
+		return ""
+	}
+	return fmt.Sprintf("%s: sent %v, stored %v", path, a, b)
+}
+
+func (v *verifier) Report(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	fmt.Fprintf(w, "Verified %d orders: %d missing after %s, %d mismatched\n",
+		v.latencies.Len()+len(v.missing), len(v.missing), v.timeout, len(v.mismatched))
+	sort.Strings(v.missing)
+	for _, id := range v.missing {
+		fmt.Fprintf(w, "  missing %s\n", id)
+	}
+	ids := make([]string, 0, len(v.mismatched))
+	for id := range v.mismatched {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(w, "  mismatched %s: %s\n", id, v.mismatched[id])
+	}
+	v.latencies.Report(w, "End-to-end")
+}
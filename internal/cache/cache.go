@@ -0,0 +1,62 @@
+package cache
+
+import "wb-tech-backend/internal/models"
+
+// Cache stores orders by their ids.
+type Cache interface {
+	Get(orderId string) (models.Order, bool)
+	Set(order models.Order)
+	// SetIfNewer caches order unless the same order with not less ingestion sequence number is already cached.
+	SetIfNewer(order models.Order)
+	Delete(orderId string)
+}
+
+var (
+	_ Cache = (*Memory)(nil)
+	_ Cache = (*Redis)(nil)
+	_ Cache = (*Tiered)(nil)
+)
+
+// Tiered is a two-level cache: orders are looked up in local Memory first and then in shared L2 cache.
+// Listing, counting and snapshots of embedded Memory cover only locally cached orders.
+type Tiered struct {
+	*Memory
+	// L2 is nil if there is only local cache.
+	L2 Cache
+}
+
+func NewTiered(l1 *Memory, l2 Cache) *Tiered {
+	return &Tiered{Memory: l1, L2: l2}
+}
+
+func (t *Tiered) Get(orderId string) (models.Order, bool) {
+	if order, ok := t.Memory.Get(orderId); ok || t.L2 == nil {
+		return order, ok
+	}
+	order, ok := t.L2.Get(orderId)
+	if ok {
+		t.Memory.SetIfNewer(order)
+	}
+	return order, ok
+}
+
+func (t *Tiered) Set(order models.Order) {
+	t.Memory.Set(order)
+	if t.L2 != nil {
+		t.L2.Set(order)
+	}
+}
+
+func (t *Tiered) SetIfNewer(order models.Order) {
+	t.Memory.SetIfNewer(order)
+	if t.L2 != nil {
+		t.L2.SetIfNewer(order)
+	}
+}
+
+func (t *Tiered) Delete(orderId string) {
+	t.Memory.Delete(orderId)
+	if t.L2 != nil {
+		t.L2.Delete(orderId)
+	}
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTieredPromotesL2Hit(t *testing.T) {
+	l2, _ := newTestRedis(t, nil)
+	l2.Set(testOrder("a", 1))
+	tiered := NewTiered(NewMemory(), l2)
+	if _, ok := tiered.Memory.Get("a"); ok {
+		t.Fatal("order is cached locally before lookup")
+	}
+	if order, ok := tiered.Get("a"); !ok || order.Seq != 1 {
+		t.Fatalf("got %+v, %v", order, ok)
+	}
+	if _, ok := tiered.Memory.Get("a"); !ok {
+		t.Fatal("L2 hit is not promoted into L1")
+	}
+}
+
+func TestTieredWithoutL2(t *testing.T) {
+	tiered := NewTiered(NewMemory(), nil)
+	tiered.Set(testOrder("a", 1))
+	tiered.SetIfNewer(testOrder("a", 0))
+	if order, ok := tiered.Get("a"); !ok || order.Seq != 1 {
+		t.Fatalf("got %+v, %v", order, ok)
+	}
+	tiered.Delete("a")
+	if _, ok := tiered.Get("a"); ok {
+		t.Fatal("deleted order is cached")
+	}
+}
+
+func TestMemoryPageBounds(t *testing.T) {
+	m := NewMemory()
+	for seq := int64(1); seq <= 3; seq++ {
+		m.Set(testOrder(string(rune('a'+seq)), seq))
+	}
+	if orders, total := m.Page(0, 2); total != 3 || len(orders) != 2 || orders[0].Seq != 3 {
+		t.Fatalf("got %d orders of %d", len(orders), total)
+	}
+	for _, offset := range []int{-10, 3, 100} {
+		if orders, total := m.Page(offset, 2); total != 3 || len(orders) != 0 {
+			t.Fatalf("offset %d: got %d orders of %d", offset, len(orders), total)
+		}
+	}
+}
+
+func TestMemoryPageFollowsChanges(t *testing.T) {
+	m := NewMemory()
+	m.Set(testOrder("a", 1))
+	m.Set(testOrder("b", 2))
+	m.Set(testOrder("c", 3))
+	m.Set(testOrder("a", 4))
+	m.SetIfNewer(testOrder("b", 1))
+	m.Delete("c")
+	orders, total := m.Page(0, 10)
+	if total != 2 || len(orders) != 2 || orders[0].OrderId != "a" || orders[1].OrderId != "b" {
+		t.Fatalf("got %+v of %d", orders, total)
+	}
+}
+
+func TestMisses(t *testing.T) {
+	m := NewMisses(time.Hour, 2)
+	m.Add("a")
+	m.Add("b")
+	m.Add("c")
+	if !m.Has("a") || !m.Has("b") || m.Has("c") {
+		t.Fatal("misses are not bounded by size")
+	}
+	m.Delete("a")
+	if m.Has("a") {
+		t.Fatal("deleted miss is remembered")
+	}
+
+	expired := NewMisses(-time.Second, 2)
+	expired.Add("a")
+	if expired.Has("a") {
+		t.Fatal("expired miss is remembered")
+	}
+
+	var disabled *Misses
+	disabled.Add("a")
+	if disabled.Has("a") {
+		t.Fatal("nil misses remember id")
+	}
+}
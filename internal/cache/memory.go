@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"sort"
+	"sync"
+
+	"wb-tech-backend/internal/models"
+)
+
+// Memory is an in-process orders cache safe for concurrent use.
+type Memory struct {
+	mu     sync.RWMutex
+	orders map[string]models.Order
+	// bySeq indexes cached orders by ingestion sequence number in ascending order for paging.
+	bySeq []seqKey
+}
+
+type seqKey struct {
+	seq     int64
+	orderId string
+}
+
+func (k seqKey) less(other seqKey) bool {
+	if k.seq != other.seq {
+		return k.seq < other.seq
+	}
+	return k.orderId < other.orderId
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		orders: make(map[string]models.Order),
+	}
+}
+
+func (m *Memory) Get(orderId string) (models.Order, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	order, ok := m.orders[orderId]
+	return order, ok
+}
+
+func (m *Memory) Set(order models.Order) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(order)
+}
+
+// SetIfNewer caches order unless the same order with not less ingestion sequence number is already cached.
+func (m *Memory) SetIfNewer(order models.Order) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cached, ok := m.orders[order.OrderId]; ok && cached.Seq >= order.Seq {
+		return
+	}
+	m.set(order)
+}
+
+func (m *Memory) Delete(orderId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cached, ok := m.orders[orderId]; ok {
+		m.unindex(cached)
+		delete(m.orders, orderId)
+	}
+}
+
+func (m *Memory) set(order models.Order) {
+	if cached, ok := m.orders[order.OrderId]; ok {
+		m.unindex(cached)
+	}
+	m.orders[order.OrderId] = order
+	key := seqKey{seq: order.Seq, orderId: order.OrderId}
+	i := m.search(key)
+	m.bySeq = append(m.bySeq, seqKey{})
+	copy(m.bySeq[i+1:], m.bySeq[i:])
+	m.bySeq[i] = key
+}
+
+func (m *Memory) unindex(order models.Order) {
+	key := seqKey{seq: order.Seq, orderId: order.OrderId}
+	if i := m.search(key); i < len(m.bySeq) && m.bySeq[i] == key {
+		m.bySeq = append(m.bySeq[:i], m.bySeq[i+1:]...)
+	}
+}
+
+// search returns position of key in index or position where it must be inserted.
+func (m *Memory) search(key seqKey) int {
+	return sort.Search(len(m.bySeq), func(i int) bool {
+		return !m.bySeq[i].less(key)
+	})
+}
+
+func (m *Memory) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.orders)
+}
+
+// All returns all cached orders in no particular order.
+func (m *Memory) All() []models.Order {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	orders := make([]models.Order, 0, len(m.orders))
+	for _, order := range m.orders {
+		orders = append(orders, order)
+	}
+	return orders
+}
+
+// Page returns up to limit cached orders starting from offset, newest ingested first,
+// and total number of cached orders. Page is empty if offset or limit is negative.
+func (m *Memory) Page(offset, limit int) ([]models.Order, int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	total := len(m.bySeq)
+	if offset < 0 || limit < 0 || offset >= total {
+		return []models.Order{}, total
+	}
+	orders := make([]models.Order, 0, min(limit, total-offset))
+	for i := total - 1 - offset; i >= 0 && len(orders) < limit; i-- {
+		orders = append(orders, m.orders[m.bySeq[i].orderId])
+	}
+	return orders, total
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Misses remembers ids of orders missing from storage for ttl, so repeated lookups of unknown ids
+// don't reach storage. At most size ids are remembered. Nil Misses remembers nothing.
+type Misses struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	expires map[string]time.Time
+}
+
+func NewMisses(ttl time.Duration, size int) *Misses {
+	return &Misses{
+		ttl:     ttl,
+		size:    size,
+		expires: make(map[string]time.Time),
+	}
+}
+
+// Add remembers that order is missing from storage.
+func (m *Misses) Add(orderId string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if len(m.expires) >= m.size {
+		for id, expires := range m.expires {
+			if now.After(expires) {
+				delete(m.expires, id)
+			}
+		}
+		if len(m.expires) >= m.size {
+			return
+		}
+	}
+	m.expires[orderId] = now.Add(m.ttl)
+}
+
+// Has reports whether order was recently found missing from storage.
+func (m *Misses) Has(orderId string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expires, ok := m.expires[orderId]
+	if ok && time.Now().After(expires) {
+		delete(m.expires, orderId)
+		return false
+	}
+	return ok
+}
+
+// Delete forgets the miss, it is called when order is stored.
+func (m *Misses) Delete(orderId string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.expires, orderId)
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"log/slog"
+	"time"
+
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultRedisTimeout = 100 * time.Millisecond
+
+// sealedField authenticates orders encrypted by Sealer.
+const sealedField = "cached_order"
+
+// Sealer encrypts cached orders, since they contain personal data.
+type Sealer interface {
+	Seal(field string, plaintext []byte) ([]byte, error)
+	Open(field string, sealed []byte) ([]byte, error)
+}
+
+// setIfNewerScript stores order in hash unless stored order has not less sequence number.
+var setIfNewerScript = redis.NewScript(`
+local seq = redis.call('HGET', KEYS[1], 'seq')
+if seq and tonumber(seq) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'seq', ARGV[1], 'order', ARGV[2])
+if tonumber(ARGV[3]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// Redis is an orders cache shared by service replicas. Every order is stored in hash with its ingestion
+// sequence number and gob encoded order. Cache is best effort: Redis errors are logged and reported as misses.
+type Redis struct {
+	client *redis.Client
+	config core.RedisConfig
+	sealer Sealer
+}
+
+// NewRedis returns Redis cache, orders are encrypted by sealer unless it is nil.
+func NewRedis(cfg core.RedisConfig, sealer Sealer) *Redis {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultRedisTimeout
+	}
+	return &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:     cfg.Addr,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		config: cfg,
+		sealer: sealer,
+	}
+}
+
+func (r *Redis) Get(orderId string) (models.Order, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+	defer cancel()
+	data, err := r.client.HGet(ctx, r.key(orderId), "order").Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.Order{}, false
+	}
+	if err != nil {
+		slog.Warn("Error with get order from redis", "order_uid", orderId, "error", err)
+		return models.Order{}, false
+	}
+	order, err := r.decode(data)
+	if err != nil {
+		slog.Warn("Error with decode order from redis", "order_uid", orderId, "error", err)
+		return models.Order{}, false
+	}
+	return order, true
+}
+
+func (r *Redis) Set(order models.Order) {
+	data, err := r.encode(order)
+	if err != nil {
+		slog.Warn("Error with encode order for redis", "order_uid", order.OrderId, "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+	defer cancel()
+	key := r.key(order.OrderId)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "seq", order.Seq, "order", data)
+		if r.config.TTL > 0 {
+			pipe.PExpire(ctx, key, r.config.TTL)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("Error with set order to redis", "order_uid", order.OrderId, "error", err)
+	}
+}
+
+func (r *Redis) SetIfNewer(order models.Order) {
+	data, err := r.encode(order)
+	if err != nil {
+		slog.Warn("Error with encode order for redis", "order_uid", order.OrderId, "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+	defer cancel()
+	err = setIfNewerScript.Run(ctx, r.client, []string{r.key(order.OrderId)}, order.Seq, data, r.config.TTL.Milliseconds()).Err()
+	if err != nil {
+		slog.Warn("Error with set order to redis", "order_uid", order.OrderId, "error", err)
+	}
+}
+
+func (r *Redis) Delete(orderId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+	defer cancel()
+	if err := r.client.Del(ctx, r.key(orderId)).Err(); err != nil {
+		slog.Warn("Error with delete order from redis", "order_uid", orderId, "error", err)
+	}
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+func (r *Redis) key(orderId string) string {
+	return r.config.KeyPrefix + orderId
+}
+
+func (r *Redis) encode(order models.Order) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(order); err != nil {
+		return nil, err
+	}
+	if r.sealer == nil {
+		return buf.Bytes(), nil
+	}
+	return r.sealer.Seal(sealedField, buf.Bytes())
+}
+
+func (r *Redis) decode(data []byte) (models.Order, error) {
+	var err error
+	if r.sealer != nil {
+		if data, err = r.sealer.Open(sealedField, data); err != nil {
+			return models.Order{}, err
+		}
+	}
+	var order models.Order
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&order)
+	return order, err
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/pkg/keyring"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedis(t *testing.T, sealer Sealer) (*Redis, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	r := NewRedis(core.RedisConfig{Addr: mr.Addr(), KeyPrefix: "orders:", TTL: time.Hour}, sealer)
+	t.Cleanup(func() { _ = r.Close() })
+	return r, mr
+}
+
+func newTestKeyring(t *testing.T) *keyring.Keyring {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keyring")
+	if _, err := keyring.AddKey(path); err != nil {
+		t.Fatal(err)
+	}
+	k, err := keyring.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func testOrder(id string, seq int64) models.Order {
+	return models.Order{OrderId: id, Seq: seq, CustomerId: "customer", Delivery: models.Delivery{Name: "Test Testov"}}
+}
+
+func TestRedisGetSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		sealer func(t *testing.T) Sealer
+	}{
+		{name: "plain", sealer: func(*testing.T) Sealer { return nil }},
+		{name: "sealed", sealer: func(t *testing.T) Sealer { return newTestKeyring(t) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mr := newTestRedis(t, tt.sealer(t))
+			if _, ok := r.Get("a"); ok {
+				t.Fatal("got order from empty cache")
+			}
+			r.Set(testOrder("a", 1))
+			order, ok := r.Get("a")
+			if !ok || order.OrderId != "a" || order.Seq != 1 || order.Delivery.Name != "Test Testov" {
+				t.Fatalf("got %+v, %v", order, ok)
+			}
+			stored := []byte(mr.HGet("orders:a", "order"))
+			if sealed := !bytes.Contains(stored, []byte("Test Testov")); sealed != (tt.name == "sealed") {
+				t.Fatalf("order is sealed: %v", sealed)
+			}
+		})
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	r, mr := newTestRedis(t, nil)
+	r.Set(testOrder("a", 1))
+	r.SetIfNewer(testOrder("b", 1))
+	mr.FastForward(59 * time.Minute)
+	if _, ok := r.Get("a"); !ok {
+		t.Fatal("order expired before TTL")
+	}
+	mr.FastForward(time.Minute)
+	for _, id := range []string{"a", "b"} {
+		if _, ok := r.Get(id); ok {
+			t.Fatalf("order %s not expired after TTL", id)
+		}
+	}
+}
+
+func TestRedisSetIfNewer(t *testing.T) {
+	r, _ := newTestRedis(t, nil)
+	r.SetIfNewer(testOrder("a", 2))
+	r.SetIfNewer(testOrder("a", 1))
+	if order, _ := r.Get("a"); order.Seq != 2 {
+		t.Fatalf("older order replaced newer one, seq=%d", order.Seq)
+	}
+	r.SetIfNewer(testOrder("a", 3))
+	if order, _ := r.Get("a"); order.Seq != 3 {
+		t.Fatalf("newer order not cached, seq=%d", order.Seq)
+	}
+}
+
+func TestRedisDelete(t *testing.T) {
+	r, mr := newTestRedis(t, nil)
+	r.Set(testOrder("a", 1))
+	r.Delete("a")
+	if _, ok := r.Get("a"); ok {
+		t.Fatal("deleted order is cached")
+	}
+	if mr.Exists("orders:a") {
+		t.Fatal("deleted order is stored in redis")
+	}
+}
+
+func TestRedisOutageIsMiss(t *testing.T) {
+	r, mr := newTestRedis(t, nil)
+	r.Set(testOrder("a", 1))
+	mr.Close()
+	if _, ok := r.Get("a"); ok {
+		t.Fatal("got order from unavailable redis")
+	}
+	// writes are best effort and must not panic
+	r.Set(testOrder("b", 1))
+	r.SetIfNewer(testOrder("b", 2))
+	r.Delete("b")
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"wb-tech-backend/internal/models"
+)
+
+// ErrCorruptSnapshot is returned when snapshot file is truncated, damaged or written by unsupported version.
+var ErrCorruptSnapshot = errors.New("corrupt cache snapshot")
+
+const (
+	snapshotMagic   = "WBCACHE"
+	snapshotVersion = 1
+)
+
+// SnapshotMeta is stored in snapshot header.
+type SnapshotMeta struct {
+	// Seq is the high-water mark: the greatest ingestion sequence number of snapshot orders.
+	Seq     int64
+	Count   int
+	Created time.Time
+	// KeyId and WrappedKey identify data key payload is encrypted with, payload is not encrypted if KeyId is empty.
+	KeyId      string
+	WrappedKey []byte
+}
+
+// Encode returns gob encoded cached orders and their high-water mark.
+func (m *Memory) Encode() ([]byte, SnapshotMeta, error) {
+	orders := m.All()
+	meta := SnapshotMeta{Count: len(orders), Created: time.Now()}
+	for _, order := range orders {
+		meta.Seq = max(meta.Seq, order.Seq)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(orders); err != nil {
+		return nil, SnapshotMeta{}, err
+	}
+	return buf.Bytes(), meta, nil
+}
+
+// Restore caches orders encoded by Encode. Newer cached orders are not overwritten.
+func (m *Memory) Restore(payload []byte) (int, error) {
+	var orders []models.Order
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&orders); err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrCorruptSnapshot, err)
+	}
+	for _, order := range orders {
+		m.SetIfNewer(order)
+	}
+	return len(orders), nil
+}
+
+// WriteSnapshot atomically writes snapshot file: magic, version, length-prefixed gob encoded meta and payload
+// and SHA-256 checksum of all preceding bytes.
+func WriteSnapshot(path string, meta SnapshotMeta, payload []byte) error {
+	var header bytes.Buffer
+	if err := gob.NewEncoder(&header).Encode(meta); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(snapshotMagic) + 2 + 4 + header.Len() + 8 + len(payload) + sha256.Size)
+	buf.WriteString(snapshotMagic)
+	_ = binary.Write(&buf, binary.BigEndian, uint16(snapshotVersion))
+	_ = binary.Write(&buf, binary.BigEndian, uint32(header.Len()))
+	buf.Write(header.Bytes())
+	_ = binary.Write(&buf, binary.BigEndian, uint64(len(payload)))
+	buf.Write(payload)
+	sum := sha256.Sum256(buf.Bytes())
+	buf.Write(sum[:])
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadSnapshot reads and verifies snapshot file written by WriteSnapshot.
+func ReadSnapshot(path string) (SnapshotMeta, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SnapshotMeta{}, nil, err
+	}
+	if len(data) < len(snapshotMagic)+2+sha256.Size || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return SnapshotMeta{}, nil, fmt.Errorf("%w: bad header", ErrCorruptSnapshot)
+	}
+	body, sum := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if expected := sha256.Sum256(body); !bytes.Equal(sum, expected[:]) {
+		return SnapshotMeta{}, nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptSnapshot)
+	}
+	body = body[len(snapshotMagic):]
+	if version := binary.BigEndian.Uint16(body); version != snapshotVersion {
+		return SnapshotMeta{}, nil, fmt.Errorf("%w: unsupported version %d", ErrCorruptSnapshot, version)
+	}
+	body = body[2:]
+
+	header, body, err := lengthPrefixed(body, 4)
+	if err != nil {
+		return SnapshotMeta{}, nil, err
+	}
+	var meta SnapshotMeta
+	if err = gob.NewDecoder(bytes.NewReader(header)).Decode(&meta); err != nil {
+		return SnapshotMeta{}, nil, fmt.Errorf("%w: %w", ErrCorruptSnapshot, err)
+	}
+	payload, body, err := lengthPrefixed(body, 8)
+	if err != nil {
+		return SnapshotMeta{}, nil, err
+	}
+	if len(body) != 0 {
+		return SnapshotMeta{}, nil, fmt.Errorf("%w: trailing data", ErrCorruptSnapshot)
+	}
+	return meta, payload, nil
+}
+
+// lengthPrefixed splits data into value prefixed by big-endian length of size bytes and the rest.
+func lengthPrefixed(data []byte, size int) ([]byte, []byte, error) {
+	if len(data) < size {
+		return nil, nil, fmt.Errorf("%w: truncated", ErrCorruptSnapshot)
+	}
+	var n uint64
+	if size == 4 {
+		n = uint64(binary.BigEndian.Uint32(data))
+	} else {
+		n = binary.BigEndian.Uint64(data)
+	}
+	data = data[size:]
+	if uint64(len(data)) < n {
+		return nil, nil, fmt.Errorf("%w: truncated", ErrCorruptSnapshot)
+	}
+	return data[:n], data[n:], nil
+}
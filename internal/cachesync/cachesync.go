@@ -0,0 +1,102 @@
+package cachesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/nats"
+
+	"github.com/nats-io/stan.go"
+)
+
+// Change notifies service replicas that order was stored, updated or deleted. It carries no order data,
+// replicas read changed order from storage.
+type Change struct {
+	OrderId string `json:"order_uid"`
+	// Seq is ingestion sequence number of changed order, replicas having the same or newer order ignore change.
+	Seq int64 `json:"seq"`
+	// Origin is id of replica that made the change.
+	Origin string `json:"origin"`
+}
+
+// Applier applies order changes made by other replicas to local cache.
+type Applier interface {
+	ApplyOrderChange(ctx context.Context, orderId string, seq int64) error
+}
+
+type Deps struct {
+	Conn    stan.Conn
+	Applier Applier
+}
+
+// Broadcaster publishes changes of orders stored by this replica to NATS subject and applies changes
+// published by other replicas, so caches of all replicas converge.
+type Broadcaster struct {
+	Deps
+	subject string
+	origin  string
+}
+
+// New connects to nats-streaming with client id unique for the replica, since every replica must receive
+// all changes.
+func New(applier Applier, cfg core.CacheSyncConfig, clusterId, natsUrl string) (*Broadcaster, error) {
+	origin, err := nats.ReplicaId()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := stan.Connect(clusterId, cfg.ClientId+"-"+origin, stan.NatsURL(natsUrl))
+	if err != nil {
+		return nil, err
+	}
+	return &Broadcaster{
+		Deps: Deps{
+			Conn:    conn,
+			Applier: applier,
+		},
+		subject: cfg.Subject,
+		origin:  origin,
+	}, nil
+}
+
+// NotifyOrderChanged publishes change of order to other replicas.
+func (b *Broadcaster) NotifyOrderChanged(order models.Order) error {
+	data, err := json.Marshal(Change{OrderId: order.OrderId, Seq: order.Seq, Origin: b.origin})
+	if err != nil {
+		return err
+	}
+	if err = b.Conn.Publish(b.subject, data); err != nil {
+		return fmt.Errorf("publish change of order %s: %w", order.OrderId, err)
+	}
+	return nil
+}
+
+// Run applies changes published by other replicas after subscription until ctx is done. Changes made
+// while replica was down are picked up by cache warm-up.
+func (b *Broadcaster) Run(ctx context.Context) error {
+	sub, err := b.Conn.Subscribe(b.subject, func(msg *stan.Msg) {
+		var change Change
+		if err := json.Unmarshal(msg.Data, &change); err != nil {
+			slog.Warn("Skip invalid cache change", "error", err)
+			return
+		}
+		if change.Origin == b.origin {
+			return
+		}
+		if err := b.Applier.ApplyOrderChange(ctx, change.OrderId, change.Seq); err != nil {
+			slog.Error("Error with apply cache change", "order_uid", change.OrderId, "error", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return sub.Close()
+}
+
+func (b *Broadcaster) Close() error {
+	return b.Conn.Close()
+}
@@ -0,0 +1,79 @@
+package cachesync
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// channel is notified by triggers with order_uid of changed orders.
+const channel = "orders_changed"
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Refresher refreshes cached order from storage.
+type Refresher interface {
+	RefreshOrder(ctx context.Context, orderId string) error
+}
+
+// Listener refreshes cached orders changed in storage, including changes made directly in SQL,
+// by listening to notifications of storage triggers on dedicated connection.
+type Listener struct {
+	Refresher Refresher
+	url       string
+}
+
+func NewListener(refresher Refresher, url string) *Listener {
+	return &Listener{Refresher: refresher, url: url}
+}
+
+// Run listens to notifications until ctx is done and reconnects with exponential delay if connection fails.
+// Changes made while connection is lost are not refreshed.
+func (l *Listener) Run(ctx context.Context) error {
+	delay := minReconnectDelay
+	for {
+		listened, err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if listened {
+			delay = minReconnectDelay
+		}
+		slog.Warn("Error with listen order changes, reconnecting", "error", err, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// listen connects to storage and handles notifications until error. It reports whether listening was started.
+func (l *Listener) listen(ctx context.Context) (bool, error) {
+	conn, err := pgx.Connect(ctx, l.url)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = conn.Close(context.Background())
+	}()
+	if _, err = conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return false, err
+	}
+	slog.Info("Listening to order changes", "channel", channel)
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		if err = l.Refresher.RefreshOrder(ctx, notification.Payload); err != nil {
+			slog.Error("Error with refresh changed order", "order_uid", notification.Payload, "error", err)
+		}
+	}
+}
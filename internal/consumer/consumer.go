@@ -0,0 +1,38 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"wb-tech-backend/internal/models"
+)
+
+const (
+	TypeNats  = "nats"
+	TypeKafka = "kafka"
+)
+
+// Consumer is an interface for source of order messages.
+type Consumer interface {
+	// Run consumes messages until ctx is done.
+	Run(ctx context.Context) error
+	Close() error
+}
+
+// OrderSaver persists orders received by Consumer.
+type OrderSaver interface {
+	AddOrder(ctx context.Context, order models.Order) error
+}
+
+// DecodeOrder unmarshals message payload into order and validates it.
+func DecodeOrder(data []byte) (models.Order, error) {
+	var order models.Order
+	if err := json.Unmarshal(data, &order); err != nil {
+		return models.Order{}, fmt.Errorf("unmarshal message: %w", err)
+	}
+	if err := order.Validate(); err != nil {
+		return models.Order{}, fmt.Errorf("validate order: %w", err)
+	}
+	return order, nil
+}
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"time"
+
 	"wb-tech-backend/internal/pkg/web"
 
 	"github.com/spf13/viper"
@@ -15,14 +17,160 @@ type NatsConfig struct {
 	Subject   string `yaml:"subject"`
 }
 
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+	GroupId string   `yaml:"groupId"`
+}
+
+type ConsumerConfig struct {
+	Type string `yaml:"type"`
+}
+
+type OutboxConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// ClientId is prefix of nats-streaming client id, replica id is appended to it.
+	ClientId      string        `yaml:"clientId"`
+	Subject       string        `yaml:"subject"`
+	Interval      time.Duration `yaml:"interval"`
+	BatchSize     uint64        `yaml:"batchSize"`
+	RetryDelay    time.Duration `yaml:"retryDelay"`
+	MaxRetryDelay time.Duration `yaml:"maxRetryDelay"`
+	// Lease is the time claimed events are skipped by other relays, it must exceed publishing of a batch.
+	Lease time.Duration `yaml:"lease"`
+}
+
+type GRPCConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Listen        string        `yaml:"listen"`
+	DrainInterval time.Duration `yaml:"drainInterval"`
+}
+
+type CacheSyncConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// ClientId is prefix of nats-streaming client id, replica id is appended to it.
+	ClientId string `yaml:"clientId"`
+	Subject  string `yaml:"subject"`
+}
+
+type StreamConfig struct {
+	Heartbeat   time.Duration `yaml:"heartbeat"`
+	Buffer      int           `yaml:"buffer"`
+	ReplayLimit uint64        `yaml:"replayLimit"`
+}
+
+const (
+	defaultStreamHeartbeat   = 15 * time.Second
+	defaultStreamReplayLimit = 500
+)
+
+// setDefaults replaces zero heartbeat and replay limit, they would stop stream from working.
+func (c *StreamConfig) setDefaults() {
+	if c.Heartbeat <= 0 {
+		c.Heartbeat = defaultStreamHeartbeat
+	}
+	if c.ReplayLimit == 0 {
+		c.ReplayLimit = defaultStreamReplayLimit
+	}
+}
+
+type CacheConfig struct {
+	// WarmUpBatch is the number of orders read from storage at once during warm-up.
+	WarmUpBatch int `yaml:"warmUpBatch"`
+	// WarmUpLimit limits warm-up to the most recent orders, all orders are loaded if 0.
+	WarmUpLimit uint64 `yaml:"warmUpLimit"`
+	// WarmUpMaxAge limits warm-up to orders created within the duration, all orders are loaded if 0.
+	WarmUpMaxAge time.Duration `yaml:"warmUpMaxAge"`
+	// SnapshotFile enables loading cache from the file on startup and saving it every SnapshotInterval.
+	SnapshotFile     string        `yaml:"snapshotFile"`
+	SnapshotInterval time.Duration `yaml:"snapshotInterval"`
+	// MissTTL is the time order missing from storage is not looked up again, misses are not cached if 0.
+	MissTTL time.Duration `yaml:"missTTL"`
+	// ListenChanges enables refreshing cached orders changed in storage, see migration notify_order_changes.
+	ListenChanges bool `yaml:"listenChanges"`
+	// Redis enables shared second-level cache.
+	Redis RedisConfig `yaml:"redis"`
+}
+
+type RedisConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Addr      string `yaml:"addr"`
+	Password  string `yaml:"password"`
+	DB        int    `yaml:"db"`
+	KeyPrefix string `yaml:"keyPrefix"`
+	// TTL of cached orders, orders don't expire if 0.
+	TTL     time.Duration `yaml:"ttl"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+type AuthConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// AnonymousRole is role of callers when authentication is disabled, "reader" if empty.
+	AnonymousRole string         `yaml:"anonymousRole"`
+	ApiKeys       []ApiKeyConfig `yaml:"apiKeys"`
+	JWT           JWTConfig      `yaml:"jwt"`
+}
+
+type ApiKeyConfig struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+	// Role is one of "reader", "support" or "admin".
+	Role string `yaml:"role"`
+}
+
+type JWTConfig struct {
+	// HS256Secret enables HS256 tokens signed with the secret.
+	HS256Secret string `yaml:"hs256Secret"`
+	// JWKSFile enables RS256 tokens signed with keys from local JWKS file.
+	JWKSFile  string `yaml:"jwksFile"`
+	Issuer    string `yaml:"issuer"`
+	Audience  string `yaml:"audience"`
+	RoleClaim string `yaml:"roleClaim"`
+}
+
+// RateLimitConfig represents default limits of requests rate and limits of particular routes.
+type RateLimitConfig struct {
+	Enabled bool              `yaml:"enabled"`
+	RPS     float64           `yaml:"rps"`
+	Burst   int               `yaml:"burst"`
+	Routes  []RouteRateConfig `yaml:"routes"`
+}
+
+type RouteRateConfig struct {
+	Path  string  `yaml:"path"`
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
+}
+
 type StorageConfig struct {
 	URL string `yaml:"url" env-required:"true"`
+	// KeyringFile enables encryption of delivery personal data with keys from the file.
+	KeyringFile string `yaml:"keyringFile"`
+	// ReadTimeout and WriteTimeout limit duration of single reading and writing statements.
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// IsolationLevel of transactions: "read committed", "repeatable read" or "serializable", server default if empty.
+	IsolationLevel string `yaml:"isolationLevel"`
+	// TxRetries is the maximum number of retries of transactions failed with serialization failure,
+	// deadlock or lost connection.
+	TxRetries       int           `yaml:"txRetries"`
+	TxRetryDelay    time.Duration `yaml:"txRetryDelay"`
+	TxMaxRetryDelay time.Duration `yaml:"txMaxRetryDelay"`
 }
 
 type Config struct {
-	Storage StorageConfig    `yaml:"storage"`
-	Server  web.ServerConfig `yaml:"server"`
-	Nats    NatsConfig       `yaml:"nats"`
+	Storage   StorageConfig    `yaml:"storage"`
+	Server    web.ServerConfig `yaml:"server"`
+	Nats      NatsConfig       `yaml:"nats"`
+	Kafka     KafkaConfig      `yaml:"kafka"`
+	Consumer  ConsumerConfig   `yaml:"consumer"`
+	Outbox    OutboxConfig     `yaml:"outbox"`
+	Stream    StreamConfig     `yaml:"stream"`
+	Cache     CacheConfig      `yaml:"cache"`
+	CacheSync CacheSyncConfig  `yaml:"cacheSync"`
+	GRPC      GRPCConfig       `yaml:"grpc"`
+	Auth      AuthConfig       `yaml:"auth"`
+	RateLimit RateLimitConfig  `yaml:"rateLimit"`
 }
 
 func ParseConfig(loader *viper.Viper) (*Config, error) {
@@ -33,5 +181,6 @@ func ParseConfig(loader *viper.Viper) (*Config, error) {
 	if err := loader.Unmarshal(cfg); err != nil {
 		return nil, err
 	}
+	cfg.Stream.setDefaults()
 	return cfg, nil
 }
@@ -0,0 +1,182 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"wb-tech-backend/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// maxXLSXRows is the maximum number of rows of Excel sheet.
+const maxXLSXRows = 1048576
+
+const xlsxSheet = "Orders"
+
+// columns are columns of flat exports, every item of order makes its own row.
+var columns = []string{
+	"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id", "delivery_service",
+	"shardkey", "sm_id", "date_created", "oof_shard",
+	"delivery_name", "delivery_phone", "delivery_zip", "delivery_city", "delivery_address", "delivery_region", "delivery_email",
+	"payment_transaction", "payment_request_id", "payment_currency", "payment_provider", "payment_amount", "payment_dt",
+	"payment_bank", "payment_delivery_cost", "payment_goods_total", "payment_custom_fee",
+	"item_chrt_id", "item_track_number", "item_price", "item_rid", "item_name", "item_sale", "item_size",
+	"item_total_price", "item_nm_id", "item_brand", "item_status",
+}
+
+// Writer writes orders in export format.
+type Writer interface {
+	Write(order models.Order) error
+	// Close flushes buffered data. It does not close underlying writer.
+	Close() error
+}
+
+// ContentType returns MIME type and file extension of format.
+func ContentType(format string) (string, bool) {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8", true
+	case FormatNDJSON:
+		return "application/x-ndjson", true
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", true
+	default:
+		return "", false
+	}
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// rows flattens order into rows of columns values, one row per item.
+func rows(o models.Order) [][]string {
+	order := []string{
+		o.OrderId, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerId, o.DeliveryService,
+		o.Shardkey, strconv.Itoa(o.SmId), o.DateCreated.Format(time.RFC3339), o.OofShard,
+		o.Delivery.Name, o.Delivery.Phone, o.Delivery.Zip, o.Delivery.City, o.Delivery.Address, o.Delivery.Region, o.Delivery.Email,
+		o.Payment.Transaction, o.Payment.RequestId, o.Payment.Currency, o.Payment.Provider, strconv.Itoa(o.Payment.Amount),
+		strconv.FormatInt(o.Payment.PaymentDt, 10), o.Payment.Bank, strconv.Itoa(o.Payment.DeliveryCost),
+		strconv.Itoa(o.Payment.GoodsTotal), strconv.Itoa(o.Payment.CustomFee),
+	}
+	if len(o.Items) == 0 {
+		return [][]string{append(order, make([]string, len(columns)-len(order))...)}
+	}
+	result := make([][]string, 0, len(o.Items))
+	for _, i := range o.Items {
+		row := append(append(make([]string, 0, len(columns)), order...),
+			strconv.Itoa(i.ChrtId), i.TrackNumber, strconv.Itoa(i.Price), i.RId, i.Name, strconv.Itoa(i.Sale), i.Size,
+			strconv.Itoa(i.TotalPrice), strconv.Itoa(i.NmId), i.Brand, strconv.Itoa(i.Status),
+		)
+		result = append(result, row)
+	}
+	return result
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(order models.Order) error {
+	return c.w.WriteAll(rows(order))
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Write(order models.Order) error {
+	return n.enc.Encode(order)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// xlsxWriter writes rows with excelize stream writer, which keeps written rows in temporary
+// file rather than memory. The workbook is written to w on Close.
+type xlsxWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName("Sheet1", xlsxSheet); err != nil {
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{w: w, file: file, stream: stream}
+	if err = x.writeRow(columns); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) Write(order models.Order) error {
+	for _, row := range rows(order) {
+		if err := x.writeRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *xlsxWriter) writeRow(row []string) error {
+	if x.row >= maxXLSXRows {
+		return fmt.Errorf("xlsx sheet can't have more than %d rows", maxXLSXRows)
+	}
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(row))
+	for i, v := range row {
+		values[i] = v
+	}
+	return x.stream.SetRow(cell, values)
+}
+
+func (x *xlsxWriter) Close() error {
+	defer x.file.Close()
+	if err := x.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := x.file.WriteTo(x.w)
+	return err
+}
@@ -0,0 +1,90 @@
+package grpc_server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"wb-tech-backend/internal/grpc_server/pb"
+	"wb-tech-backend/internal/http_server/auth"
+	"wb-tech-backend/internal/http_server/pii"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey int
+
+const (
+	principalKey contextKey = iota
+	policyKey
+)
+
+// permissions are permissions required by methods of OrderService.
+var permissions = map[string]auth.Permission{
+	pb.OrderService_GetOrder_FullMethodName:    auth.PermReadOrder,
+	pb.OrderService_ListOrders_FullMethodName:  auth.PermListOrders,
+	pb.OrderService_CreateOrder_FullMethodName: auth.PermWriteOrders,
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize authenticates caller by the same credentials as HTTP API passed in metadata, checks permission
+// of OrderService method and resolves personal data policy. Methods of other services are public.
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	perm, ok := permissions[method]
+	if !ok {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(http.Header, len(md))
+	for k, v := range md {
+		header[http.CanonicalHeaderKey(k)] = v
+	}
+	p, err := s.Authenticator.Authenticate(header)
+	if err != nil {
+		slog.Debug("Error with authentication", "error", err)
+		return nil, status.Error(codes.Unauthenticated, auth.ErrUnauthenticated.Error())
+	}
+	if !p.Can(perm) {
+		return nil, status.Errorf(codes.PermissionDenied, "permission %s required", perm)
+	}
+	policy, err := pii.Resolve(p, header.Get(pii.ReasonHeader), "method", method)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	ctx = context.WithValue(ctx, principalKey, p)
+	return context.WithValue(ctx, policyKey, policy), nil
+}
+
+// policyFromContext returns personal data policy resolved by authorize. Personal data is masked if there is none.
+func policyFromContext(ctx context.Context) pii.Policy {
+	p, _ := ctx.Value(policyKey).(pii.Policy)
+	return p
+}
+
+// serverStream replaces context of stream with authorized one.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
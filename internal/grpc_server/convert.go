@@ -0,0 +1,120 @@
+package grpc_server
+
+import (
+	"wb-tech-backend/internal/grpc_server/pb"
+	"wb-tech-backend/internal/models"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProto(o models.Order) *pb.Order {
+	items := make([]*pb.Item, 0, len(o.Items))
+	for _, i := range o.Items {
+		items = append(items, &pb.Item{
+			ChrtId:      int64(i.ChrtId),
+			TrackNumber: i.TrackNumber,
+			Price:       int64(i.Price),
+			Rid:         i.RId,
+			Name:        i.Name,
+			Sale:        int64(i.Sale),
+			Size:        i.Size,
+			TotalPrice:  int64(i.TotalPrice),
+			NmId:        int64(i.NmId),
+			Brand:       i.Brand,
+			Status:      int64(i.Status),
+		})
+	}
+	return &pb.Order{
+		OrderUid:    o.OrderId,
+		TrackNumber: o.TrackNumber,
+		Entry:       o.Entry,
+		Delivery: &pb.Delivery{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: &pb.Payment{
+			Transaction:  o.Payment.Transaction,
+			RequestId:    o.Payment.RequestId,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       int64(o.Payment.Amount),
+			PaymentDt:    o.Payment.PaymentDt,
+			Bank:         o.Payment.Bank,
+			DeliveryCost: int64(o.Payment.DeliveryCost),
+			GoodsTotal:   int64(o.Payment.GoodsTotal),
+			CustomFee:    int64(o.Payment.CustomFee),
+		},
+		Items:             items,
+		Locale:            o.Locale,
+		InternalSignature: o.InternalSignature,
+		CustomerId:        o.CustomerId,
+		DeliveryService:   o.DeliveryService,
+		Shardkey:          o.Shardkey,
+		SmId:              int64(o.SmId),
+		DateCreated:       timestamppb.New(o.DateCreated),
+		OofShard:          o.OofShard,
+	}
+}
+
+func fromProto(o *pb.Order) models.Order {
+	items := make([]models.Item, 0, len(o.GetItems()))
+	for _, i := range o.GetItems() {
+		items = append(items, models.Item{
+			ChrtId:      int(i.GetChrtId()),
+			TrackNumber: i.GetTrackNumber(),
+			Price:       int(i.GetPrice()),
+			RId:         i.GetRid(),
+			Name:        i.GetName(),
+			Sale:        int(i.GetSale()),
+			Size:        i.GetSize(),
+			TotalPrice:  int(i.GetTotalPrice()),
+			NmId:        int(i.GetNmId()),
+			Brand:       i.GetBrand(),
+			Status:      int(i.GetStatus()),
+		})
+	}
+	d, p := o.GetDelivery(), o.GetPayment()
+	order := models.Order{
+		OrderId:     o.GetOrderUid(),
+		TrackNumber: o.GetTrackNumber(),
+		Entry:       o.GetEntry(),
+		Delivery: models.Delivery{
+			Name:    d.GetName(),
+			Phone:   d.GetPhone(),
+			Zip:     d.GetZip(),
+			City:    d.GetCity(),
+			Address: d.GetAddress(),
+			Region:  d.GetRegion(),
+			Email:   d.GetEmail(),
+		},
+		Payment: models.Payment{
+			Transaction:  p.GetTransaction(),
+			RequestId:    p.GetRequestId(),
+			Currency:     p.GetCurrency(),
+			Provider:     p.GetProvider(),
+			Amount:       int(p.GetAmount()),
+			PaymentDt:    p.GetPaymentDt(),
+			Bank:         p.GetBank(),
+			DeliveryCost: int(p.GetDeliveryCost()),
+			GoodsTotal:   int(p.GetGoodsTotal()),
+			CustomFee:    int(p.GetCustomFee()),
+		},
+		Items:             items,
+		Locale:            o.GetLocale(),
+		InternalSignature: o.GetInternalSignature(),
+		CustomerId:        o.GetCustomerId(),
+		DeliveryService:   o.GetDeliveryService(),
+		Shardkey:          o.GetShardkey(),
+		SmId:              int(o.GetSmId()),
+		OofShard:          o.GetOofShard(),
+	}
+	if o.GetDateCreated() != nil {
+		order.DateCreated = o.GetDateCreated().AsTime()
+	}
+	return order
+}
@@ -0,0 +1,78 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"wb-tech-backend/internal/grpc_server/pb"
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ordersServer struct {
+	pb.UnimplementedOrderServiceServer
+	Service *service.Service
+}
+
+func (s *ordersServer) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.Order, error) {
+	if req.GetOrderUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+	}
+	order, err := s.Service.GetOrder(ctx, req.GetOrderUid())
+	if errors.Is(err, service.ErrOrderNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		slog.Debug("Error with getting order", "error", err)
+		return nil, internalError(err)
+	}
+	return toProto(policyFromContext(ctx).Order(order)), nil
+}
+
+func (s *ordersServer) ListOrders(req *pb.ListOrdersRequest, stream pb.OrderService_ListOrdersServer) error {
+	ctx := stream.Context()
+	orders, err := s.Service.ListOfOrders(ctx)
+	if err != nil {
+		slog.Debug("Error with getting orders", "error", err)
+		return internalError(err)
+	}
+	filter := models.OrderFilter{CustomerId: req.GetCustomerId(), DeliveryService: req.GetDeliveryService()}
+	policy := policyFromContext(ctx)
+	for _, order := range orders {
+		if !filter.Match(order) {
+			continue
+		}
+		if err = stream.Send(toProto(policy.Order(order))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ordersServer) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.Order, error) {
+	if req.GetOrder() == nil {
+		return nil, status.Error(codes.InvalidArgument, "order is required")
+	}
+	order := fromProto(req.GetOrder())
+	if err := order.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.Service.AddOrder(ctx, order); err != nil {
+		slog.Debug("Error with adding order", "error", err)
+		return nil, internalError(err)
+	}
+	return toProto(policyFromContext(ctx).Order(order)), nil
+}
+
+// internalError converts service error to status, storage timeouts are reported as unavailability,
+// so clients may retry.
+func internalError(err error) error {
+	if errors.Is(err, service.ErrStorageTimeout) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,84 @@
+package grpc_server
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/grpc_server/pb"
+	"wb-tech-backend/internal/http_server/auth"
+	"wb-tech-backend/internal/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type Deps struct {
+	Service       *service.Service
+	Authenticator *auth.Authenticator
+}
+
+// Server serves orders API over gRPC with reflection and health services.
+type Server struct {
+	Deps
+	config core.GRPCConfig
+	server *grpc.Server
+	health *health.Server
+}
+
+func New(serv *service.Service) (*Server, error) {
+	authenticator, err := auth.NewAuthenticator(serv.Config.Auth)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Deps: Deps{
+			Service:       serv,
+			Authenticator: authenticator,
+		},
+		config: serv.Config.GRPC,
+		health: health.NewServer(),
+	}
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryAuth),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+	)
+	pb.RegisterOrderServiceServer(s.server, &ordersServer{Service: serv})
+	healthpb.RegisterHealthServer(s.server, s.health)
+	reflection.Register(s.server)
+	return s, nil
+}
+
+// Run serves requests until Shutdown is called.
+func (s *Server) Run(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return err
+	}
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(pb.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s.server.Serve(lis)
+}
+
+// Shutdown stops accepting requests and waits for running ones up to drain interval.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.health.Shutdown()
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	timer := time.NewTimer(s.config.DrainInterval)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		s.server.Stop()
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+	return nil
+}
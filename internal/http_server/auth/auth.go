@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"wb-tech-backend/internal/core"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const principalKey = "auth.principal"
+
+// ErrUnauthenticated is returned to callers without valid credentials, details are only logged.
+var ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Name string
+	Role Role
+
+	authenticated bool
+}
+
+// Authenticated reports whether principal presented credentials, which is false when authentication is disabled.
+func (p Principal) Authenticated() bool {
+	return p.authenticated
+}
+
+func (p Principal) Can(perm Permission) bool {
+	return p.Role.Can(perm)
+}
+
+// FromContext returns principal authenticated by Authenticator.Middleware.
+func FromContext(ctx *gin.Context) (Principal, bool) {
+	v, ok := ctx.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
+
+// Authenticator authenticates requests by static API keys or JWT.
+type Authenticator struct {
+	config    core.AuthConfig
+	anonymous Principal
+	apiKeys   map[[sha256.Size]byte]Principal
+	rsaKeys   map[string]*rsa.PublicKey
+	parser    *jwt.Parser
+}
+
+func NewAuthenticator(cfg core.AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		config:    cfg,
+		anonymous: Principal{Name: "anonymous", Role: Role(cfg.AnonymousRole)},
+		apiKeys:   make(map[[sha256.Size]byte]Principal),
+	}
+	if a.anonymous.Role == "" {
+		a.anonymous.Role = RoleReader
+	}
+	if !a.anonymous.Role.Valid() {
+		return nil, fmt.Errorf("unknown anonymous role %q", cfg.AnonymousRole)
+	}
+	if cfg.JWT.RoleClaim == "" {
+		a.config.JWT.RoleClaim = "role"
+	}
+	for _, k := range cfg.ApiKeys {
+		role := Role(k.Role)
+		if k.Key == "" || !role.Valid() {
+			return nil, fmt.Errorf("api key %q must have key and valid role", k.Name)
+		}
+		a.apiKeys[sha256.Sum256([]byte(k.Key))] = Principal{Name: k.Name, Role: role}
+	}
+
+	methods := make([]string, 0)
+	if cfg.JWT.HS256Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWT.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWT.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) > 0 {
+		opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+		if cfg.JWT.Issuer != "" {
+			opts = append(opts, jwt.WithIssuer(cfg.JWT.Issuer))
+		}
+		if cfg.JWT.Audience != "" {
+			opts = append(opts, jwt.WithAudience(cfg.JWT.Audience))
+		}
+		a.parser = jwt.NewParser(opts...)
+	}
+	return a, nil
+}
+
+// Middleware authenticates request and stores principal in context. Requests without valid
+// credentials are rejected with 401.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		p, err := a.Authenticate(ctx.Request.Header)
+		if err != nil {
+			slog.Debug("Error with authentication", "error", err)
+			ctx.Header("WWW-Authenticate", `Bearer realm="orders"`)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": ErrUnauthenticated.Error(),
+			})
+			return
+		}
+		ctx.Set(principalKey, p)
+	}
+}
+
+// Authenticate returns principal presenting credentials in header. When authentication is disabled
+// every caller is an anonymous principal with configured role.
+func (a *Authenticator) Authenticate(header http.Header) (Principal, error) {
+	if !a.config.Enabled {
+		return a.anonymous, nil
+	}
+	p, err := a.authenticate(header)
+	if err != nil {
+		return Principal{}, err
+	}
+	p.authenticated = true
+	return p, nil
+}
+
+// Require rejects requests of principals without permission with 403.
+func Require(perm Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		p, ok := FromContext(ctx)
+		if !ok || !p.Can(perm) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("permission %s required", perm),
+			})
+		}
+	}
+}
+
+func (a *Authenticator) authenticate(header http.Header) (Principal, error) {
+	if key := header.Get("X-API-Key"); key != "" {
+		return a.authenticateApiKey(key)
+	}
+	token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+	// JWT always has three dot separated parts, API keys are opaque strings.
+	if strings.Count(token, ".") == 2 && a.parser != nil {
+		return a.authenticateJWT(token)
+	}
+	return a.authenticateApiKey(token)
+}
+
+func (a *Authenticator) authenticateApiKey(key string) (Principal, error) {
+	p, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return Principal{}, ErrUnauthenticated
+	}
+	return p, nil
+}
+
+func (a *Authenticator) authenticateJWT(tokenString string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(tokenString, claims, a.keyFunc)
+	if err != nil {
+		return Principal{}, err
+	}
+	sub, _ := claims.GetSubject()
+	role := highest(rolesFromClaim(claims[a.config.JWT.RoleClaim]))
+	if role == "" {
+		return Principal{}, fmt.Errorf("token of %q has no valid role", sub)
+	}
+	return Principal{Name: sub, Role: role}, nil
+}
+
+func (a *Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return []byte(a.config.JWT.HS256Secret), nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		key, ok := a.rsaKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
+
+// rolesFromClaim accepts role claim as a single string or as an array of strings.
+func rolesFromClaim(claim interface{}) []Role {
+	switch v := claim.(type) {
+	case string:
+		return []Role{Role(v)}
+	case []interface{}:
+		roles := make([]Role, 0, len(v))
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				roles = append(roles, Role(s))
+			}
+		}
+		return roles
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package auth
+
+// Role is a caller role. Every role includes permissions of the previous one.
+type Role string
+
+const (
+	RoleReader  Role = "reader"
+	RoleSupport Role = "support"
+	RoleAdmin   Role = "admin"
+)
+
+type Permission string
+
+const (
+	// PermReadOrder allows reading single order.
+	PermReadOrder Permission = "orders:read"
+	// PermListOrders allows listing and streaming orders.
+	PermListOrders Permission = "orders:list"
+	// PermReadPII allows reading customers personal data.
+	PermReadPII Permission = "pii:read"
+	// PermUnmaskPII allows reading customers personal data on request with audited reason.
+	PermUnmaskPII Permission = "pii:unmask"
+	// PermWriteOrders allows creating and changing orders.
+	PermWriteOrders Permission = "orders:write"
+	// PermReadReports allows reading aggregated reports over orders.
+	PermReadReports Permission = "reports:read"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleReader:  {PermReadOrder},
+	RoleSupport: {PermReadOrder, PermListOrders, PermUnmaskPII, PermReadReports},
+	RoleAdmin:   {PermReadOrder, PermListOrders, PermReadPII, PermUnmaskPII, PermReadReports, PermWriteOrders},
+}
+
+// rank orders roles from the least to the most privileged.
+var rank = map[Role]int{
+	RoleReader:  1,
+	RoleSupport: 2,
+	RoleAdmin:   3,
+}
+
+func (r Role) Valid() bool {
+	_, ok := rank[r]
+	return ok
+}
+
+func (r Role) Can(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// highest returns the most privileged of valid roles or empty role if there are none.
+func highest(roles []Role) Role {
+	var best Role
+	for _, r := range roles {
+		if rank[r] > rank[best] {
+			best = r
+		}
+	}
+	return best
+}
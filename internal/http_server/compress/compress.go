@@ -0,0 +1,144 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	encodingGzip   = "gzip"
+	encodingBrotli = "br"
+)
+
+// Middleware compresses responses with brotli or gzip depending on Accept-Encoding of request.
+// Strong ETag of compressed response gets encoding suffix, because compressed and identity
+// representations are not byte-equal; use MatchETag to compare it with If-None-Match.
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		encoding := negotiate(ctx.GetHeader("Accept-Encoding"))
+		ctx.Header("Vary", "Accept-Encoding")
+		if encoding == "" || ctx.Request.Method == http.MethodHead {
+			return
+		}
+		w := &writer{ResponseWriter: ctx.Writer, encoding: encoding}
+		ctx.Writer = w
+		defer w.close()
+		ctx.Next()
+	}
+}
+
+// MatchETag reports whether If-None-Match header value matches etag of response, ignoring
+// encoding suffix added by Middleware.
+func MatchETag(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || trimEncoding(tag) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func trimEncoding(tag string) string {
+	for _, encoding := range []string{encodingGzip, encodingBrotli} {
+		if t, ok := strings.CutSuffix(tag, "-"+encoding+`"`); ok {
+			return t + `"`
+		}
+	}
+	return tag
+}
+
+// negotiate returns the most preferred supported encoding. Brotli wins over gzip with equal quality.
+func negotiate(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encodingGzip && name != encodingBrotli || q <= 0 {
+			continue
+		}
+		if q > bestQ || q == bestQ && name == encodingBrotli {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+type flushWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// writer compresses body of response. Compression is decided when header is written,
+// responses without body and already encoded responses are passed as is.
+type writer struct {
+	gin.ResponseWriter
+	encoding    string
+	compressor  flushWriter
+	wroteHeader bool
+}
+
+func (w *writer) WriteHeader(code int) {
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	if etag := h.Get("ETag"); strings.HasPrefix(etag, `"`) {
+		h.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+w.encoding+`"`)
+	}
+	if code >= http.StatusOK && code != http.StatusNoContent && code != http.StatusNotModified &&
+		h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		switch w.encoding {
+		case encodingBrotli:
+			w.compressor = brotli.NewWriter(w.ResponseWriter)
+		default:
+			w.compressor = gzip.NewWriter(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *writer) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.compressor == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.compressor.Write(data)
+}
+
+func (w *writer) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush flushes compressed data, so streamed responses such as server-sent events reach client.
+func (w *writer) Flush() {
+	if w.compressor != nil {
+		_ = w.compressor.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *writer) close() {
+	if w.compressor != nil {
+		_ = w.compressor.Close()
+	}
+}
@@ -0,0 +1,62 @@
+package gql
+
+import (
+	"context"
+	"sync"
+
+	"wb-tech-backend/internal/models"
+)
+
+// itemsFetcher loads items by their ids in one query.
+type itemsFetcher func(ctx context.Context, ids []int64) (map[int64]models.Item, error)
+
+// itemsLoader batches loading of items requested while resolving one query. Load only registers ids
+// and returns thunk; the first resolved thunk fetches items of all registered ids at once.
+type itemsLoader struct {
+	fetch itemsFetcher
+
+	mu      sync.Mutex
+	pending []int64
+	batch   *itemsBatch
+}
+
+type itemsBatch struct {
+	once  sync.Once
+	items map[int64]models.Item
+	err   error
+}
+
+func newItemsLoader(fetch itemsFetcher) *itemsLoader {
+	return &itemsLoader{fetch: fetch}
+}
+
+// Load returns thunk resolving items with given ids in the same order.
+func (l *itemsLoader) Load(ctx context.Context, ids []int64) func() (interface{}, error) {
+	l.mu.Lock()
+	if l.batch == nil {
+		l.batch = &itemsBatch{}
+	}
+	batch := l.batch
+	l.pending = append(l.pending, ids...)
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		batch.once.Do(func() {
+			l.mu.Lock()
+			keys := l.pending
+			l.pending, l.batch = nil, nil
+			l.mu.Unlock()
+			batch.items, batch.err = l.fetch(ctx, keys)
+		})
+		if batch.err != nil {
+			return nil, batch.err
+		}
+		items := make([]models.Item, 0, len(ids))
+		for _, id := range ids {
+			if item, ok := batch.items[id]; ok {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+}
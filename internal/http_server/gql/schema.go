@@ -0,0 +1,226 @@
+package gql
+
+import (
+	"context"
+	"errors"
+
+	"wb-tech-backend/internal/http_server/auth"
+	"wb-tech-backend/internal/http_server/pii"
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/service"
+
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+var (
+	errListForbidden = errors.New("permission " + string(auth.PermListOrders) + " required")
+	errNegativeLimit = errors.New("limit must not be negative")
+)
+
+// Params is a GraphQL request.
+type Params struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// request is state of a single GraphQL request shared by resolvers.
+type request struct {
+	service   *service.Service
+	principal auth.Principal
+	policy    pii.Policy
+	items     *itemsLoader
+}
+
+type requestKey struct{}
+
+func requestFromContext(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
+
+// order is resolved order. Items of orders read from storage page by page are loaded by itemsLoader.
+type order struct {
+	models.Order
+	itemsIds    []int64
+	itemsLoaded bool
+}
+
+// Execute executes GraphQL query on behalf of principal. Personal data in results is shaped by policy.
+func Execute(ctx context.Context, serv *service.Service, principal auth.Principal, policy pii.Policy, params Params) *graphql.Result {
+	req := &request{
+		service:   serv,
+		principal: principal,
+		policy:    policy,
+		items:     newItemsLoader(serv.OrderItems),
+	}
+	return graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  params.Query,
+		OperationName:  params.OperationName,
+		VariableValues: params.Variables,
+		Context:        context.WithValue(ctx, requestKey{}, req),
+	})
+}
+
+var schema = mustSchema()
+
+func mustSchema() graphql.Schema {
+	deliveryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Delivery",
+		Fields: graphql.Fields{
+			"name":    &graphql.Field{Type: graphql.String},
+			"phone":   &graphql.Field{Type: graphql.String},
+			"zip":     &graphql.Field{Type: graphql.String},
+			"city":    &graphql.Field{Type: graphql.String},
+			"address": &graphql.Field{Type: graphql.String},
+			"region":  &graphql.Field{Type: graphql.String},
+			"email":   &graphql.Field{Type: graphql.String},
+		},
+	})
+	paymentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Payment",
+		Fields: graphql.Fields{
+			"transaction":   &graphql.Field{Type: graphql.String},
+			"request_id":    &graphql.Field{Type: graphql.String},
+			"currency":      &graphql.Field{Type: graphql.String},
+			"provider":      &graphql.Field{Type: graphql.String},
+			"amount":        &graphql.Field{Type: graphql.Int},
+			"payment_dt":    &graphql.Field{Type: graphql.Int},
+			"bank":          &graphql.Field{Type: graphql.String},
+			"delivery_cost": &graphql.Field{Type: graphql.Int},
+			"goods_total":   &graphql.Field{Type: graphql.Int},
+			"custom_fee":    &graphql.Field{Type: graphql.Int},
+		},
+	})
+	itemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Item",
+		Fields: graphql.Fields{
+			"chrt_id":      &graphql.Field{Type: graphql.Int},
+			"track_number": &graphql.Field{Type: graphql.String},
+			"price":        &graphql.Field{Type: graphql.Int},
+			"rid":          &graphql.Field{Type: graphql.String},
+			"name":         &graphql.Field{Type: graphql.String},
+			"sale":         &graphql.Field{Type: graphql.Int},
+			"size":         &graphql.Field{Type: graphql.String},
+			"total_price":  &graphql.Field{Type: graphql.Int},
+			"nm_id":        &graphql.Field{Type: graphql.Int},
+			"brand":        &graphql.Field{Type: graphql.String},
+			"status":       &graphql.Field{Type: graphql.Int},
+		},
+	})
+	orderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"order_uid":          orderField(graphql.String, func(o models.Order) interface{} { return o.OrderId }),
+			"track_number":       orderField(graphql.String, func(o models.Order) interface{} { return o.TrackNumber }),
+			"entry":              orderField(graphql.String, func(o models.Order) interface{} { return o.Entry }),
+			"delivery":           orderField(deliveryType, func(o models.Order) interface{} { return o.Delivery }),
+			"payment":            orderField(paymentType, func(o models.Order) interface{} { return o.Payment }),
+			"locale":             orderField(graphql.String, func(o models.Order) interface{} { return o.Locale }),
+			"internal_signature": orderField(graphql.String, func(o models.Order) interface{} { return o.InternalSignature }),
+			"customer_id":        orderField(graphql.String, func(o models.Order) interface{} { return o.CustomerId }),
+			"delivery_service":   orderField(graphql.String, func(o models.Order) interface{} { return o.DeliveryService }),
+			"shardkey":           orderField(graphql.String, func(o models.Order) interface{} { return o.Shardkey }),
+			"sm_id":              orderField(graphql.Int, func(o models.Order) interface{} { return o.SmId }),
+			"date_created":       orderField(graphql.DateTime, func(o models.Order) interface{} { return o.DateCreated }),
+			"oof_shard":          orderField(graphql.String, func(o models.Order) interface{} { return o.OofShard }),
+			"items": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(itemType))),
+				Resolve: resolveItems,
+			},
+		},
+	})
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"order": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"order_uid": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: resolveOrder,
+			},
+			"orders": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(orderType))),
+				Description: "Orders matching filter, newest first.",
+				Args: graphql.FieldConfigArgument{
+					"customer_id":      &graphql.ArgumentConfig{Type: graphql.String},
+					"delivery_service": &graphql.ArgumentConfig{Type: graphql.String},
+					"limit":            &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+					"offset":           &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: resolveOrders,
+			},
+		},
+	})
+	s, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func orderField(t graphql.Output, get func(models.Order) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(order).Order), nil
+		},
+	}
+}
+
+func resolveOrder(p graphql.ResolveParams) (interface{}, error) {
+	req := requestFromContext(p.Context)
+	o, err := req.service.GetOrder(p.Context, p.Args["order_uid"].(string))
+	if errors.Is(err, service.ErrOrderNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return order{Order: req.policy.Order(o), itemsLoaded: true}, nil
+}
+
+func resolveOrders(p graphql.ResolveParams) (interface{}, error) {
+	req := requestFromContext(p.Context)
+	if !req.principal.Can(auth.PermListOrders) {
+		return nil, errListForbidden
+	}
+	limit, offset := p.Args["limit"].(int), p.Args["offset"].(int)
+	switch {
+	case limit < 0:
+		return nil, errNegativeLimit
+	case limit == 0:
+		limit = defaultLimit
+	case limit > maxLimit:
+		limit = maxLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	filter := models.OrderFilter{}
+	filter.CustomerId, _ = p.Args["customer_id"].(string)
+	filter.DeliveryService, _ = p.Args["delivery_service"].(string)
+	records, err := req.service.FindOrders(p.Context, filter, uint64(limit), uint64(offset))
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]order, 0, len(records))
+	for _, r := range records {
+		orders = append(orders, order{Order: req.policy.Order(r.Order), itemsIds: r.ItemsIds})
+	}
+	return orders, nil
+}
+
+func resolveItems(p graphql.ResolveParams) (interface{}, error) {
+	o := p.Source.(order)
+	if o.itemsLoaded {
+		return o.Items, nil
+	}
+	return requestFromContext(p.Context).items.Load(p.Context, o.itemsIds), nil
+}
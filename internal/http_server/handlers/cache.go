@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"wb-tech-backend/internal/http_server/auth"
+	"wb-tech-backend/internal/http_server/compress"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jsonCached writes v as JSON with strong ETag computed from its content and responds with 304
+// if client already has it. Clients must revalidate cached response, as stored orders can be updated.
+func jsonCached(ctx *gin.Context, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	// responses of authenticated callers depend on their role and must not be stored by shared caches
+	cacheControl := "public, no-cache"
+	if p, ok := auth.FromContext(ctx); ok && p.Authenticated() {
+		cacheControl = "private, no-cache"
+	}
+	ctx.Header("Cache-Control", cacheControl)
+	ctx.Header("ETag", etag)
+	if compress.MatchETag(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Status(http.StatusNotModified)
+		return nil
+	}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	return nil
+}
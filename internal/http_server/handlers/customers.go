@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"wb-tech-backend/internal/http_server/pii"
+	"wb-tech-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// GetCustomerOrders returns page of customer orders newest first, paginated by limit and offset query parameters.
+func GetCustomerOrders(ctx *gin.Context, service *service.Service) error {
+	var page struct {
+		Limit  uint64 `form:"limit"`
+		Offset uint64 `form:"offset"`
+	}
+	if err := ctx.ShouldBindQuery(&page); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	if page.Limit == 0 {
+		page.Limit = defaultPageLimit
+	}
+	if page.Limit > maxPageLimit {
+		page.Limit = maxPageLimit
+	}
+	orders, err := service.CustomerOrders(ctx, ctx.Param("id"), page.Limit, page.Offset)
+	if err != nil {
+		slog.Debug("Error with getting customer orders", "error", err)
+		return err
+	}
+	ctx.JSON(http.StatusOK, pii.FromContext(ctx).Orders(orders))
+	return nil
+}
+
+func GetCustomerSummary(ctx *gin.Context, serv *service.Service) error {
+	summary, err := serv.CustomerSummary(ctx, ctx.Param("id"))
+	if errors.Is(err, service.ErrCustomerNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	if err != nil {
+		slog.Debug("Error with getting customer summary", "error", err)
+		return err
+	}
+	ctx.JSON(http.StatusOK, pii.FromContext(ctx).CustomerSummary(summary))
+	return nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"wb-tech-backend/internal/export"
+	"wb-tech-backend/internal/http_server/pii"
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportOrders streams orders matching filter as file of format given by format query parameter.
+func ExportOrders(ctx *gin.Context, service *service.Service) error {
+	format := ctx.DefaultQuery("format", export.FormatCSV)
+	contentType, ok := export.ContentType(format)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be one of csv, ndjson, xlsx",
+		})
+		return nil
+	}
+	var filter models.OrderFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", `attachment; filename="orders.`+format+`"`)
+	ctx.Status(http.StatusOK)
+	w, err := export.NewWriter(format, ctx.Writer)
+	if err != nil {
+		return err
+	}
+	policy := pii.FromContext(ctx)
+	err = service.ExportOrders(ctx, filter, func(orders []models.Order) error {
+		for _, order := range orders {
+			if err := w.Write(policy.Order(order)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		// response is already started, the client gets truncated file
+		slog.Error("Error with exporting orders", "format", format, "error", err)
+		ctx.Abort()
+	}
+	return nil
+}
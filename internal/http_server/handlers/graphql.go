@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"wb-tech-backend/internal/http_server/auth"
+	"wb-tech-backend/internal/http_server/gql"
+	"wb-tech-backend/internal/http_server/pii"
+	"wb-tech-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQL executes GraphQL query passed in JSON body of POST request or in query of GET request.
+func GraphQL(ctx *gin.Context, service *service.Service) error {
+	var params gql.Params
+	if ctx.Request.Method == http.MethodGet {
+		params.Query, params.OperationName = ctx.Query("query"), ctx.Query("operationName")
+		if v := ctx.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &params.Variables); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{
+					"error": "variables must be JSON object",
+				})
+				return nil
+			}
+		}
+	} else if err := ctx.BindJSON(&params); err != nil {
+		slog.Debug("Error with graphql request", "error", err)
+		return nil
+	}
+	if params.Query == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "query is required",
+		})
+		return nil
+	}
+	p, _ := auth.FromContext(ctx)
+	ctx.JSON(http.StatusOK, gql.Execute(ctx, service, p, pii.FromContext(ctx), params))
+	return nil
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 
+	"wb-tech-backend/internal/http_server/pii"
 	"wb-tech-backend/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -15,7 +17,7 @@ func GetOrder(ctx *gin.Context, service *service.Service) error {
 	}
 
 	if err := ctx.BindJSON(&param); err != nil {
-		slog.Debug("Error with getting order: %s", err)
+		slog.Debug("Error with getting order", "error", err)
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -23,31 +25,57 @@ func GetOrder(ctx *gin.Context, service *service.Service) error {
 	}
 	order, err := service.GetOrder(ctx, param.OrderId)
 	if err != nil {
-		slog.Debug("Error with getting order: %s", err)
+		slog.Debug("Error with getting order", "error", err)
 		return err
 	}
-	ctx.JSON(http.StatusOK, order)
+	ctx.JSON(http.StatusOK, pii.FromContext(ctx).Order(order))
 	return nil
 }
-func GetOrder2(ctx *gin.Context, service *service.Service) error {
+func GetOrder2(ctx *gin.Context, serv *service.Service) error {
 	orderId := ctx.Query("order_uid")
 	if orderId == "" {
 		slog.Debug("Error with getting order: order id is empty")
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "order_uid is required",
+		})
+		return nil
+	}
+	order, err := serv.GetOrder(ctx, orderId)
+	if errors.Is(err, service.ErrOrderNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return nil
 	}
-	order, err := service.GetOrder(ctx, orderId)
 	if err != nil {
-		slog.Debug("Error with getting order: %s", err)
+		slog.Debug("Error with getting order", "error", err)
 		return err
 	}
-	ctx.JSON(http.StatusOK, order)
-	return nil
+	return jsonCached(ctx, pii.FromContext(ctx).Order(order))
 }
 func GetOrders(ctx *gin.Context, service *service.Service) error {
 	orders, err := service.ListOfOrders(ctx)
 	if err != nil {
-		slog.Debug("Error with getting orders: %s", err)
+		slog.Debug("Error with getting orders", "error", err)
+		return err
+	}
+	ctx.JSON(http.StatusOK, pii.FromContext(ctx).Orders(orders))
+	return nil
+}
+
+func SearchOrders(ctx *gin.Context, service *service.Service) error {
+	email, phone := ctx.Query("email"), ctx.Query("phone")
+	if email == "" && phone == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": "email or phone is required",
+		})
+		return nil
+	}
+	orders, err := service.SearchOrders(ctx, email, phone)
+	if err != nil {
+		slog.Debug("Error with searching orders", "error", err)
 		return err
 	}
-	ctx.JSON(http.StatusOK, orders)
+	ctx.JSON(http.StatusOK, pii.FromContext(ctx).Orders(orders))
 	return nil
 }
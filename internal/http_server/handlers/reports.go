@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTopItems = 10
+	maxTopItems     = 100
+)
+
+// bindReportQuery binds report query parameters and responds with 400 if they are invalid.
+func bindReportQuery(ctx *gin.Context) (models.ReportQuery, bool) {
+	var q models.ReportQuery
+	err := ctx.ShouldBindQuery(&q)
+	if err == nil {
+		err = q.Validate()
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return q, false
+	}
+	return q, true
+}
+
+func RevenueReport(ctx *gin.Context, service *service.Service) error {
+	q, ok := bindReportQuery(ctx)
+	if !ok {
+		return nil
+	}
+	report, err := service.RevenueReport(ctx, q)
+	if err != nil {
+		slog.Debug("Error with revenue report", "error", err)
+		return err
+	}
+	ctx.JSON(http.StatusOK, report)
+	return nil
+}
+
+// TopItemsReport returns the most sold items, or brands if by=brand, limited by limit query parameter.
+func TopItemsReport(ctx *gin.Context, service *service.Service) error {
+	q, ok := bindReportQuery(ctx)
+	if !ok {
+		return nil
+	}
+	var params struct {
+		By    string `form:"by" binding:"omitempty,oneof=item brand"`
+		Limit int    `form:"limit" binding:"gte=0"`
+	}
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	if params.Limit == 0 {
+		params.Limit = defaultTopItems
+	}
+	if params.Limit > maxTopItems {
+		params.Limit = maxTopItems
+	}
+	report, err := service.TopItemsReport(ctx, q, params.By == "brand", params.Limit)
+	if err != nil {
+		slog.Debug("Error with top items report", "error", err)
+		return err
+	}
+	ctx.JSON(http.StatusOK, report)
+	return nil
+}
+
+func DeliveryServicesReport(ctx *gin.Context, service *service.Service) error {
+	q, ok := bindReportQuery(ctx)
+	if !ok {
+		return nil
+	}
+	report, err := service.DeliveryServicesReport(ctx, q)
+	if err != nil {
+		slog.Debug("Error with delivery services report", "error", err)
+		return err
+	}
+	ctx.JSON(http.StatusOK, report)
+	return nil
+}
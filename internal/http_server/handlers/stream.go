@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wb-tech-backend/internal/http_server/pii"
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/service"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamOrders streams stored orders as server-sent events. Event id is the order ingestion
+// sequence, so client reconnecting with Last-Event-ID header receives orders it missed.
+func StreamOrders(ctx *gin.Context, service *service.Service) error {
+	var filter models.OrderFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	lastEventId := ctx.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = ctx.Query("last_event_id")
+	}
+	var lastSeq int64
+	if lastEventId != "" {
+		seq, err := strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid last event id",
+			})
+			return nil
+		}
+		lastSeq = seq
+	}
+
+	policy := pii.FromContext(ctx)
+	sub := service.SubscribeOrders()
+	defer sub.Close()
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	if lastEventId != "" {
+		for {
+			orders, err := service.OrdersAfter(ctx, lastSeq, filter)
+			if err != nil {
+				slog.Debug("Error with replaying orders", "error", err)
+				return err
+			}
+			for _, order := range orders {
+				writeOrderEvent(ctx, policy.Order(order))
+				lastSeq = order.Seq
+			}
+			if uint64(len(orders)) < service.Config.Stream.ReplayLimit {
+				break
+			}
+		}
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(service.Config.Stream.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return nil
+		case order, ok := <-sub.C:
+			if !ok {
+				// subscriber fell behind, client resumes from the last received event
+				return nil
+			}
+			if order.Seq <= lastSeq || !filter.Match(order) {
+				continue
+			}
+			writeOrderEvent(ctx, policy.Order(order))
+			lastSeq = order.Seq
+		case t := <-heartbeat.C:
+			ctx.Render(-1, sse.Event{
+				Event: "heartbeat",
+				Data:  t.Unix(),
+			})
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+func writeOrderEvent(ctx *gin.Context, order models.Order) {
+	ctx.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(order.Seq, 10),
+		Event: "order",
+		Data:  order,
+	})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wb-tech-backend/internal/http_server/pii"
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const uiOrdersPerPage = 20
+
+// uiPage is the data rendered by UI templates.
+type uiPage struct {
+	Title      string
+	OrderId    string
+	Message    string
+	Order      *models.Order
+	Masked     bool
+	Orders     []models.Order
+	Page       int
+	TotalPages int
+	Total      int
+}
+
+func UIIndex(ctx *gin.Context, _ *service.Service) error {
+	ctx.HTML(http.StatusOK, "index.html", uiPage{Title: "Order lookup"})
+	return nil
+}
+
+func UIOrder(ctx *gin.Context, serv *service.Service) error {
+	orderId := strings.TrimSpace(ctx.Query("order_uid"))
+	if orderId == "" {
+		ctx.Redirect(http.StatusFound, "/ui")
+		return nil
+	}
+	order, err := serv.GetOrder(ctx, orderId)
+	if errors.Is(err, service.ErrOrderNotFound) {
+		ctx.HTML(http.StatusNotFound, "not_found.html", uiPage{
+			Title:   "Not found",
+			OrderId: orderId,
+			Message: fmt.Sprintf("Order %s does not exist.", orderId),
+		})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	policy := pii.FromContext(ctx)
+	order = policy.Order(order)
+	ctx.HTML(http.StatusOK, "order.html", uiPage{
+		Title:   "Order " + orderId,
+		OrderId: orderId,
+		Order:   &order,
+		Masked:  !policy.Unmasked,
+	})
+	return nil
+}
+
+func UIOrders(ctx *gin.Context, serv *service.Service) error {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	// offset of larger pages overflows, they don't exist anyway
+	page = min(page, math.MaxInt/uiOrdersPerPage)
+	orders, total, err := serv.PageOfOrders(ctx, page, uiOrdersPerPage)
+	if err != nil {
+		return err
+	}
+	totalPages := max((total+uiOrdersPerPage-1)/uiOrdersPerPage, 1)
+	if page > totalPages {
+		ctx.HTML(http.StatusNotFound, "not_found.html", uiPage{
+			Title:   "Not found",
+			Message: fmt.Sprintf("Page %d does not exist.", page),
+		})
+		return nil
+	}
+	ctx.HTML(http.StatusOK, "orders.html", uiPage{
+		Title:      "Orders",
+		Orders:     pii.FromContext(ctx).Orders(orders),
+		Page:       page,
+		TotalPages: totalPages,
+		Total:      total,
+	})
+	return nil
+}
+
+// NotFound responds with HTML page to browsers and with JSON error to API clients.
+func NotFound(ctx *gin.Context) {
+	if strings.Contains(ctx.GetHeader("Accept"), "text/html") {
+		ctx.HTML(http.StatusNotFound, "not_found.html", uiPage{
+			Title:   "Not found",
+			Message: fmt.Sprintf("Page %s does not exist.", ctx.Request.URL.Path),
+		})
+		return
+	}
+	ctx.JSON(http.StatusNotFound, gin.H{
+		"error": "not found",
+	})
+}
@@ -0,0 +1,298 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"wb-tech-backend/internal/cache"
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/http_server"
+	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/repository"
+	"wb-tech-backend/internal/service"
+	"wb-tech-backend/internal/stream"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	knownOrderId  = "b563feb7b2b84b6test"
+	knownCustomer = "test"
+	knownItemId   = 1
+)
+
+// contractCase is a request to a route of the router. Every registered route needs at least one case.
+// Requests with 400 status are expected to be rejected by the document too, only their responses are validated.
+type contractCase struct {
+	route  string
+	method string
+	url    string
+	body   string
+	header http.Header
+	status int
+}
+
+var contractCases = []contractCase{
+	{route: "/openapi.json", method: http.MethodGet, url: "/openapi.json", status: http.StatusOK},
+	{route: "/docs", method: http.MethodGet, url: "/docs", status: http.StatusOK},
+
+	{route: "/order", method: http.MethodGet, url: "/order?order_uid=" + knownOrderId, status: http.StatusOK},
+	{route: "/order", method: http.MethodGet, url: "/order?order_uid=unknown", status: http.StatusNotFound},
+	{route: "/order", method: http.MethodGet, url: "/order?order_uid=" + knownOrderId,
+		header: http.Header{"If-None-Match": {"*"}}, status: http.StatusNotModified},
+	{route: "/orders", method: http.MethodGet, url: "/orders", status: http.StatusOK},
+	{route: "/orders/export", method: http.MethodGet, url: "/orders/export?format=csv", status: http.StatusOK},
+	{route: "/orders/export", method: http.MethodGet, url: "/orders/export?format=ndjson", status: http.StatusOK},
+	{route: "/orders/export", method: http.MethodGet, url: "/orders/export?format=xlsx", status: http.StatusOK},
+	{route: "/orders/export", method: http.MethodGet, url: "/orders/export?format=pdf", status: http.StatusBadRequest},
+	{route: "/orders/search", method: http.MethodGet, url: "/orders/search?email=test@gmail.com", status: http.StatusOK},
+	{route: "/orders/stream", method: http.MethodGet, url: "/orders/stream?last_event_id=0", status: http.StatusOK},
+
+	{route: "/customers/:id/orders", method: http.MethodGet, url: "/customers/" + knownCustomer + "/orders?limit=10", status: http.StatusOK},
+	{route: "/customers/:id/summary", method: http.MethodGet, url: "/customers/" + knownCustomer + "/summary", status: http.StatusOK},
+	{route: "/customers/:id/summary", method: http.MethodGet, url: "/customers/unknown/summary", status: http.StatusNotFound},
+
+	{route: "/reports/revenue", method: http.MethodGet, url: "/reports/revenue?from=2021-11-01&to=2021-11-30&period=day", status: http.StatusOK},
+	{route: "/reports/revenue", method: http.MethodGet, url: "/reports/revenue?period=year", status: http.StatusBadRequest},
+	{route: "/reports/top-items", method: http.MethodGet, url: "/reports/top-items?by=brand&limit=5", status: http.StatusOK},
+	{route: "/reports/delivery-services", method: http.MethodGet, url: "/reports/delivery-services?group_by=provider", status: http.StatusOK},
+
+	{route: "/graphql", method: http.MethodGet, url: "/graphql?query=" + "%7B%20order(order_uid%3A%20%22" + knownOrderId + "%22)%20%7B%20order_uid%20%7D%20%7D", status: http.StatusOK},
+	{route: "/graphql", method: http.MethodPost, url: "/graphql",
+		body:   `{"query": "{ orders(limit: 5) { order_uid items { name price } } }"}`,
+		header: http.Header{"Content-Type": {"application/json"}}, status: http.StatusOK},
+
+	{route: "/ui", method: http.MethodGet, url: "/ui", status: http.StatusOK},
+	{route: "/ui/order", method: http.MethodGet, url: "/ui/order?order_uid=" + knownOrderId, status: http.StatusOK},
+	{route: "/ui/order", method: http.MethodGet, url: "/ui/order?order_uid=unknown", status: http.StatusNotFound},
+	{route: "/ui/orders", method: http.MethodGet, url: "/ui/orders?page=1", status: http.StatusOK},
+}
+
+// TestContract calls every route of the router and validates requests and responses against OpenAPI document.
+func TestContract(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	order := loadOrder(t)
+	app, err := http_server.New(newStubService(order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := loadRouter(t)
+
+	covered := make(map[string]bool)
+	for _, c := range contractCases {
+		covered[c.method+" "+c.route] = true
+	}
+	for _, route := range app.Router.Routes() {
+		if !covered[route.Method+" "+route.Path] {
+			t.Errorf("%s %s: no contract case", route.Method, route.Path)
+		}
+		req := httptest.NewRequest(route.Method, openapiPath(route.Path), nil)
+		if _, _, err := router.FindRoute(req); err != nil {
+			t.Errorf("%s %s: route is missing from OpenAPI document: %v", route.Method, route.Path, err)
+		}
+	}
+
+	for _, c := range contractCases {
+		t.Run(c.method+" "+c.url, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			var body io.Reader
+			if c.body != "" {
+				body = strings.NewReader(c.body)
+			}
+			req := httptest.NewRequest(c.method, c.url, body).WithContext(ctx)
+			for name, values := range c.header {
+				req.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+			if w.Code != c.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, c.status, w.Body.String())
+			}
+
+			validationReq := httptest.NewRequest(c.method, c.url, strings.NewReader(c.body))
+			validationReq.Header = req.Header
+			route, pathParams, err := router.FindRoute(validationReq)
+			if err != nil {
+				t.Fatalf("route is missing from OpenAPI document: %v", err)
+			}
+			input := &openapi3filter.RequestValidationInput{
+				Request:    validationReq,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				},
+			}
+			err = openapi3filter.ValidateRequest(context.Background(), input)
+			if c.status == http.StatusBadRequest && err == nil {
+				t.Fatal("invalid request matches OpenAPI document")
+			}
+			if c.status != http.StatusBadRequest && err != nil {
+				t.Fatalf("request does not match OpenAPI document: %v", err)
+			}
+			output := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 w.Code,
+				Header:                 w.Header(),
+				Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+			}
+			output.SetBodyBytes(w.Body.Bytes())
+			if err = openapi3filter.ValidateResponse(context.Background(), output); err != nil {
+				t.Fatalf("response does not match OpenAPI document: %v", err)
+			}
+		})
+	}
+}
+
+func init() {
+	// bodies of these types are described as plain strings
+	for _, contentType := range []string{"text/html", "text/event-stream", "application/x-ndjson",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"} {
+		openapi3filter.RegisterBodyDecoder(contentType, stringBodyDecoder)
+	}
+}
+
+func stringBodyDecoder(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (interface{}, error) {
+	data, err := io.ReadAll(body)
+	return string(data), err
+}
+
+func loadRouter(t *testing.T) routers.Router {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromFile("openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	doc.Servers = nil
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+// openapiPath returns concrete path of gin route, path parameters are replaced with sample values.
+func openapiPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "sample"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func loadOrder(t *testing.T) models.Order {
+	t.Helper()
+	data, err := os.ReadFile("../../../json_models/model.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order models.Order
+	if err = json.Unmarshal(data, &order); err != nil {
+		t.Fatal(err)
+	}
+	order.Seq = 1
+	return order
+}
+
+func newStubService(order models.Order) *service.Service {
+	cfg := &core.Config{
+		Stream: core.StreamConfig{Heartbeat: time.Minute, Buffer: 8, ReplayLimit: 100},
+		Auth:   core.AuthConfig{AnonymousRole: "support"},
+	}
+	orders := cache.NewTiered(cache.NewMemory(), nil)
+	orders.Set(order)
+	return &service.Service{
+		Deps: service.Deps{
+			Repository: stubRepository{order: order},
+			Cache:      orders,
+			Config:     cfg,
+			Hub:        stream.NewHub(),
+		},
+	}
+}
+
+// stubRepository stores the only order. It implements only methods called by routes, others panic.
+type stubRepository struct {
+	service.Repository
+	order models.Order
+}
+
+func (r stubRepository) GetOrderById(_ context.Context, orderId string) (models.Order, error) {
+	if orderId != r.order.OrderId {
+		return models.Order{}, nil
+	}
+	return r.order, nil
+}
+
+func (r stubRepository) GetOrdersAfterSeq(_ context.Context, seq int64, _ models.OrderFilter, _ uint64) ([]models.Order, error) {
+	if seq >= r.order.Seq {
+		return []models.Order{}, nil
+	}
+	return []models.Order{r.order}, nil
+}
+
+func (r stubRepository) FindOrders(_ context.Context, _ models.OrderFilter, _, _ uint64) ([]repository.OrderRecord, error) {
+	record := repository.OrderRecord{Order: r.order, ItemsIds: []int64{knownItemId}}
+	record.Items = nil
+	return []repository.OrderRecord{record}, nil
+}
+
+func (r stubRepository) GetItems(_ context.Context, _ []int64) (map[int64]models.Item, error) {
+	return map[int64]models.Item{knownItemId: r.order.Items[0]}, nil
+}
+
+func (r stubRepository) GetCustomerOrders(_ context.Context, _ string, _, _ uint64) ([]models.Order, error) {
+	return []models.Order{r.order}, nil
+}
+
+func (r stubRepository) GetCustomerSummary(_ context.Context, customerId string) (models.CustomerSummary, error) {
+	if customerId != r.order.CustomerId {
+		return models.CustomerSummary{CustomerId: customerId}, nil
+	}
+	return models.CustomerSummary{
+		CustomerId:      customerId,
+		OrdersCount:     1,
+		TotalSpent:      map[string]int64{r.order.Payment.Currency: int64(r.order.Payment.Amount)},
+		FirstOrderAt:    r.order.DateCreated,
+		LastOrderAt:     r.order.DateCreated,
+		FavouriteBrands: []models.BrandStat{},
+		Addresses:       []models.DeliveryAddress{},
+	}, nil
+}
+
+func (r stubRepository) RevenueReport(_ context.Context, _ models.ReportQuery) ([]models.RevenueRow, error) {
+	return []models.RevenueRow{{ReportGroup: models.ReportGroup{Currency: "RUB"}, Orders: 1, Amount: 100}}, nil
+}
+
+func (r stubRepository) TopItemsReport(_ context.Context, _ models.ReportQuery, _ bool, _ int) ([]models.TopItemRow, error) {
+	return []models.TopItemRow{{ReportGroup: models.ReportGroup{Currency: "RUB"}, Brand: "Vivienne Sabo", Quantity: 1}}, nil
+}
+
+func (r stubRepository) DeliveryServicesReport(_ context.Context, _ models.ReportQuery) ([]models.DeliveryServiceRow, error) {
+	return []models.DeliveryServiceRow{{ReportGroup: models.ReportGroup{Currency: "RUB"}, DeliveryService: "meest", Orders: 1}}, nil
+}
+
+func (r stubRepository) ExportOrders(_ context.Context, _ models.OrderFilter, _ int, handle func([]models.Order) error) error {
+	return handle([]models.Order{r.order})
+}
+
+func (r stubRepository) GetOrdersByContact(_ context.Context, _, _ string) ([]models.Order, error) {
+	return []models.Order{r.order}, nil
+}
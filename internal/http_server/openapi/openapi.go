@@ -0,0 +1,133 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"log/slog"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>wb-tech-backend API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});</script>
+</body>
+</html>`
+
+// Handler serves OpenAPI document.
+func Handler(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
+
+// UIHandler serves Swagger UI page for OpenAPI document.
+func UIHandler(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI))
+}
+
+// streamedPaths are paths of responses which are too long to be recorded for validation.
+var streamedPaths = map[string]bool{
+	"/orders/stream": true,
+	"/orders/export": true,
+}
+
+// Validator checks requests and responses of routes described by OpenAPI document.
+// It is meant for development and staging environments to catch drift between handlers and document.
+type Validator struct {
+	router routers.Router
+}
+
+func NewValidator() (*Validator, error) {
+	// schemas in validation errors make log records unreadable
+	openapi3.SchemaErrorDetailsDisabled = true
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err = doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	// servers are matched by router, document describes paths relative to any host
+	doc.Servers = nil
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{router: router}, nil
+}
+
+// Middleware rejects requests not matching document with 400 and logs responses not matching it.
+// Responses of streamedPaths are not validated.
+func (v *Validator) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route, pathParams, err := v.router.FindRoute(ctx.Request)
+		if err != nil {
+			// routes missing from document are not validated, contract test reports them
+			return
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    ctx.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+		if err = openapi3filter.ValidateRequest(ctx, input); err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if streamedPaths[route.Path] {
+			return
+		}
+
+		w := &recorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = w
+		ctx.Next()
+
+		output := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 w.Status(),
+			Header:                 w.Header(),
+			Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+		}
+		output.SetBodyBytes(w.body.Bytes())
+		if err = openapi3filter.ValidateResponse(ctx, output); err != nil {
+			slog.Warn("Response does not match OpenAPI document", "method", ctx.Request.Method,
+				"path", route.Path, "status", w.Status(), "error", err)
+		}
+	}
+}
+
+// recorder keeps copy of uncompressed response body for validation.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	return r.Write([]byte(s))
+}
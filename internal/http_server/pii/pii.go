@@ -0,0 +1,151 @@
+package pii
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"wb-tech-backend/internal/http_server/auth"
+	"wb-tech-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	policyKey = "pii.policy"
+	// ReasonHeader carries the reason of request to see unmasked personal data.
+	ReasonHeader = "X-Unmask-Reason"
+	// ReasonQuery is used instead of ReasonHeader where headers can't be set, e.g. in browser.
+	ReasonQuery = "unmask_reason"
+)
+
+var ErrUnmaskForbidden = errors.New("unmasking personal data is not permitted")
+
+// Policy shapes orders returned to caller.
+type Policy struct {
+	Unmasked bool
+}
+
+// Middleware resolves Policy of authenticated caller.
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		p, _ := auth.FromContext(ctx)
+		reason := ctx.GetHeader(ReasonHeader)
+		if reason == "" {
+			reason = ctx.Query(ReasonQuery)
+		}
+		policy, err := Resolve(p, reason,
+			"method", ctx.Request.Method,
+			"path", ctx.Request.URL.Path,
+			"query", ctx.Request.URL.RawQuery,
+		)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		ctx.Set(policyKey, policy)
+	}
+}
+
+// Resolve returns Policy of principal. Principals with PermReadPII see personal data as is, principals
+// with PermUnmaskPII see it only when they pass the reason, which is written to audit log with attrs.
+// Anonymous principals can't be audited, so they can't unmask personal data by reason.
+func Resolve(p auth.Principal, reason string, attrs ...any) (Policy, error) {
+	if reason == "" {
+		return Policy{Unmasked: p.Can(auth.PermReadPII)}, nil
+	}
+	if !p.Can(auth.PermReadPII) && (!p.Can(auth.PermUnmaskPII) || !p.Authenticated()) {
+		return Policy{}, ErrUnmaskForbidden
+	}
+	args := append([]any{"audit", true, "principal", p.Name, "role", p.Role}, attrs...)
+	slog.Info("Personal data unmasked", append(args, "reason", reason)...)
+	return Policy{Unmasked: true}, nil
+}
+
+// FromContext returns Policy resolved by Middleware. Personal data is masked if there is none.
+func FromContext(ctx *gin.Context) Policy {
+	v, _ := ctx.Get(policyKey)
+	p, _ := v.(Policy)
+	return p
+}
+
+func (p Policy) Order(order models.Order) models.Order {
+	if p.Unmasked {
+		return order
+	}
+	return MaskOrder(order)
+}
+
+func (p Policy) Orders(orders []models.Order) []models.Order {
+	if p.Unmasked {
+		return orders
+	}
+	masked := make([]models.Order, 0, len(orders))
+	for _, order := range orders {
+		masked = append(masked, MaskOrder(order))
+	}
+	return masked
+}
+
+// CustomerSummary returns summary with delivery addresses masked by policy.
+func (p Policy) CustomerSummary(summary models.CustomerSummary) models.CustomerSummary {
+	if p.Unmasked {
+		return summary
+	}
+	addresses := make([]models.DeliveryAddress, 0, len(summary.Addresses))
+	for _, a := range summary.Addresses {
+		a.Address = MaskAddress(a.Address)
+		addresses = append(addresses, a)
+	}
+	summary.Addresses = addresses
+	return summary
+}
+
+// MaskOrder returns copy of order with masked delivery personal data.
+func MaskOrder(order models.Order) models.Order {
+	order.Delivery.Name = MaskName(order.Delivery.Name)
+	order.Delivery.Phone = MaskPhone(order.Delivery.Phone)
+	order.Delivery.Email = MaskEmail(order.Delivery.Email)
+	order.Delivery.Address = MaskAddress(order.Delivery.Address)
+	return order
+}
+
+// MaskPhone keeps country code prefix and the last four digits: +79991234567 -> +7***4567.
+func MaskPhone(phone string) string {
+	r := []rune(phone)
+	if len(r) <= 6 {
+		return "***"
+	}
+	return string(r[:2]) + "***" + string(r[len(r)-4:])
+}
+
+// MaskEmail keeps the first letter and domain: john@test.com -> j***@test.com.
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	first, _ := utf8.DecodeRuneInString(local)
+	return string(first) + "***@" + domain
+}
+
+// MaskName keeps initials: Test Testov -> T*** T***.
+func MaskName(name string) string {
+	words := strings.Fields(name)
+	for i, w := range words {
+		first, _ := utf8.DecodeRuneInString(w)
+		words[i] = string(first) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+func MaskAddress(address string) string {
+	if address == "" {
+		return ""
+	}
+	return "***"
+}
@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/http_server/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is the time after which bucket of client that made no requests is dropped.
+const idleTimeout = 10 * time.Minute
+
+var throttledRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "http_throttled_requests_total",
+	Help: "Number of requests rejected by rate limiter.",
+}, []string{"route"})
+
+type bucketKey struct {
+	route  string
+	client string
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter limits requests rate of every client to every route with token buckets.
+type Limiter struct {
+	config core.RateLimitConfig
+	routes map[string]core.RouteRateConfig
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+// New returns limiter, limits of cfg.Routes override default ones for particular routes.
+func New(cfg core.RateLimitConfig) *Limiter {
+	l := &Limiter{
+		config:    cfg,
+		routes:    make(map[string]core.RouteRateConfig, len(cfg.Routes)),
+		buckets:   make(map[bucketKey]*bucket),
+		lastSweep: time.Now(),
+	}
+	for _, r := range cfg.Routes {
+		l.routes[r.Path] = r
+	}
+	return l
+}
+
+// Middleware rejects requests exceeding limit with 429 and Retry-After header. Clients are
+// identified by authenticated principal or, if authentication is disabled, by IP address.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !l.config.Enabled {
+			return
+		}
+		route := ctx.FullPath()
+		client := "ip:" + ctx.ClientIP()
+		if p, ok := auth.FromContext(ctx); ok && p.Authenticated() {
+			client = "principal:" + p.Name
+		}
+		r := l.limiter(bucketKey{route: route, client: client}).Reserve()
+		if !r.OK() {
+			l.reject(ctx, route, time.Second)
+			return
+		}
+		if delay := r.Delay(); delay > 0 {
+			r.Cancel()
+			l.reject(ctx, route, delay)
+		}
+	}
+}
+
+// FailedAuthMiddleware must run before authentication. It limits rate of requests rejected with 401 by IP
+// address, so credential guessing and floods of unauthenticated requests are throttled too. Requests of
+// authenticated clients behind the same IP address don't take tokens of the bucket.
+func (l *Limiter) FailedAuthMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !l.config.Enabled {
+			return
+		}
+		route := ctx.FullPath()
+		limiter := l.limiter(bucketKey{route: route, client: "unauthenticated:" + ctx.ClientIP()})
+		if tokens := limiter.Tokens(); tokens < 1 {
+			retryAfter := time.Second
+			if limiter.Limit() > 0 {
+				retryAfter = time.Duration((1 - tokens) / float64(limiter.Limit()) * float64(time.Second))
+			}
+			l.reject(ctx, route, retryAfter)
+			return
+		}
+		ctx.Next()
+		if ctx.Writer.Status() == http.StatusUnauthorized {
+			limiter.Allow()
+		}
+	}
+}
+
+func (l *Limiter) reject(ctx *gin.Context, route string, retryAfter time.Duration) {
+	throttledRequests.WithLabelValues(route).Inc()
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error": "too many requests",
+	})
+}
+
+func (l *Limiter) limiter(key bucketKey) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) > idleTimeout {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > idleTimeout {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		rps, burst := l.config.RPS, l.config.Burst
+		if r, ok := l.routes[key.route]; ok {
+			rps, burst = r.RPS, r.Burst
+		}
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	return b.limiter
+}
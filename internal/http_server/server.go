@@ -2,46 +2,106 @@ package http_server
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
+	"wb-tech-backend/internal/http_server/auth"
+	"wb-tech-backend/internal/http_server/compress"
 	"wb-tech-backend/internal/http_server/handlers"
+	"wb-tech-backend/internal/http_server/openapi"
+	"wb-tech-backend/internal/http_server/pii"
+	"wb-tech-backend/internal/http_server/ratelimit"
 	"wb-tech-backend/internal/pkg/web"
 	"wb-tech-backend/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+// envDev is environment in which responses are validated against OpenAPI document.
+const envDev = "dev"
+
 type App struct {
-	Server  web.Server
-	Router  *gin.Engine
-	Service *service.Service
+	Server        web.Server
+	Router        *gin.Engine
+	Service       *service.Service
+	Authenticator *auth.Authenticator
 }
 
-func New(service *service.Service) *App {
+func New(service *service.Service) (*App, error) {
+	authenticator, err := auth.NewAuthenticator(service.Config.Auth)
+	if err != nil {
+		return nil, err
+	}
 	app := &App{
-		Service: service,
+		Service:       service,
+		Authenticator: authenticator,
+	}
+	if err = app.initRoutes(); err != nil {
+		return nil, err
 	}
-	app.initRoutes()
 	app.Server = web.NewServer(service.Config.Server, app.Router)
-	return app
+	return app, nil
 }
 
 func (app *App) Start(ctx context.Context) error {
 	return app.Server.Run(ctx)
 }
 
-func (app *App) initRoutes() {
+func (app *App) initRoutes() error {
 	app.Router = gin.Default()
+	app.Router.Use(compress.Middleware())
+	if app.Service.Config.Server.Env == envDev {
+		validator, err := openapi.NewValidator()
+		if err != nil {
+			return err
+		}
+		app.Router.Use(validator.Middleware())
+	}
+	app.Router.SetHTMLTemplate(parseTemplates())
+	app.Router.NoRoute(handlers.NotFound)
+	app.Router.GET("/openapi.json", openapi.Handler)
+	app.Router.GET("/docs", openapi.UIHandler)
+
+	limiter := ratelimit.New(app.Service.Config.RateLimit)
+	api := app.Router.Group("/", limiter.FailedAuthMiddleware(), app.Authenticator.Middleware(),
+		limiter.Middleware(), pii.Middleware())
 
-	app.Router.GET("/order", app.mappedHandler(handlers.GetOrder2))
-	app.Router.GET("/orders", app.mappedHandler(handlers.GetOrders))
+	api.GET("/order", auth.Require(auth.PermReadOrder), app.mappedHandler(handlers.GetOrder2))
+	api.GET("/orders", auth.Require(auth.PermListOrders), app.mappedHandler(handlers.GetOrders))
+	api.GET("/orders/export", auth.Require(auth.PermListOrders), app.mappedHandler(handlers.ExportOrders))
+	api.GET("/orders/search", auth.Require(auth.PermListOrders), app.mappedHandler(handlers.SearchOrders))
+	api.GET("/orders/stream", auth.Require(auth.PermListOrders), app.mappedHandler(handlers.StreamOrders))
+
+	api.GET("/customers/:id/orders", auth.Require(auth.PermListOrders), app.mappedHandler(handlers.GetCustomerOrders))
+	api.GET("/customers/:id/summary", auth.Require(auth.PermListOrders), app.mappedHandler(handlers.GetCustomerSummary))
+
+	api.GET("/reports/revenue", auth.Require(auth.PermReadReports), app.mappedHandler(handlers.RevenueReport))
+	api.GET("/reports/top-items", auth.Require(auth.PermReadReports), app.mappedHandler(handlers.TopItemsReport))
+	api.GET("/reports/delivery-services", auth.Require(auth.PermReadReports), app.mappedHandler(handlers.DeliveryServicesReport))
+
+	api.GET("/graphql", auth.Require(auth.PermReadOrder), app.mappedHandler(handlers.GraphQL))
+	api.POST("/graphql", auth.Require(auth.PermReadOrder), app.mappedHandler(handlers.GraphQL))
+
+	api.GET("/ui", auth.Require(auth.PermReadOrder), app.mappedHandler(handlers.UIIndex))
+	api.GET("/ui/order", auth.Require(auth.PermReadOrder), app.mappedHandler(handlers.UIOrder))
+	api.GET("/ui/orders", auth.Require(auth.PermListOrders), app.mappedHandler(handlers.UIOrders))
+	return nil
 }
 
 func (app *App) mappedHandler(handler func(*gin.Context, *service.Service) error) gin.HandlerFunc {
 
 	return func(ctx *gin.Context) {
 
-		if err := handler(ctx, app.Service); err != nil {
+		err := handler(ctx, app.Service)
+		if errors.Is(err, service.ErrStorageTimeout) {
+			_ = ctx.Error(err)
+			ctx.Header("Retry-After", "1")
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "storage is not available, retry later",
+			})
+			return
+		}
+		if err != nil {
 			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
 		}
 	}
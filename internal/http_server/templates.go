@@ -0,0 +1,25 @@
+package http_server
+
+import (
+	"embed"
+	"html/template"
+	"time"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var templateFuncs = template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+	"formatUnix": func(sec int64) string {
+		return time.Unix(sec, 0).UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+	"add": func(a, b int) int { return a + b },
+	"sub": func(a, b int) int { return a - b },
+}
+
+func parseTemplates() *template.Template {
+	return template.Must(template.New("").Funcs(templateFuncs).ParseFS(templatesFS, "templates/*.html"))
+}
@@ -0,0 +1,361 @@
+package importer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"wb-tech-backend/internal/models"
+)
+
+// OrdersSaver stores batch of orders in one transaction and returns number of stored ones.
+type OrdersSaver interface {
+	ImportOrders(ctx context.Context, orders []models.Order) (int, error)
+}
+
+type Config struct {
+	BatchSize int
+	Workers   int
+	// StateFile keeps files imported completely, they are skipped when import is run again.
+	StateFile string
+	// RejectFile receives orders that can't be imported as JSON lines.
+	RejectFile       string
+	ProgressInterval time.Duration
+}
+
+type Deps struct {
+	Saver OrdersSaver
+}
+
+// Importer imports orders from JSON files holding an order, an array of orders or orders
+// separated by new lines. Files ending with .gz are decompressed.
+type Importer struct {
+	Deps
+	config Config
+
+	done    map[string]bool
+	stateMu sync.Mutex
+	state   *os.File
+
+	rejectMu sync.Mutex
+	rejects  *json.Encoder
+
+	read, stored, skipped, rejected atomic.Int64
+}
+
+// Summary is the result of import.
+type Summary struct {
+	Files, Read, Stored, Skipped, Rejected int64
+}
+
+// Reject is order that can't be imported.
+type Reject struct {
+	File    string          `json:"file"`
+	Index   int             `json:"index"`
+	OrderId string          `json:"order_uid,omitempty"`
+	Error   string          `json:"error"`
+	Order   json.RawMessage `json:"order,omitempty"`
+}
+
+type batch struct {
+	file *fileProgress
+	// indexes are positions of orders in file
+	indexes []int
+	orders  []models.Order
+	raw     []json.RawMessage
+}
+
+// fileProgress tracks batches of file being saved, file is done when it is read and all its batches are saved.
+type fileProgress struct {
+	path    string
+	pending sync.WaitGroup
+}
+
+func NewImporter(saver OrdersSaver, cfg Config) *Importer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.ProgressInterval <= 0 {
+		cfg.ProgressInterval = 5 * time.Second
+	}
+	return &Importer{Deps: Deps{Saver: saver}, config: cfg}
+}
+
+// Import imports files and files of directories at paths.
+func (im *Importer) Import(ctx context.Context, paths []string) (Summary, error) {
+	files, err := listFiles(paths)
+	if err != nil {
+		return Summary{}, err
+	}
+	if err = im.openState(); err != nil {
+		return Summary{}, err
+	}
+	defer im.state.Close()
+	rejects, err := os.OpenFile(im.config.RejectFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer rejects.Close()
+	im.rejects = json.NewEncoder(rejects)
+
+	batches := make(chan batch, im.config.Workers)
+	var workers sync.WaitGroup
+	for i := 0; i < im.config.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for b := range batches {
+				im.save(ctx, b)
+				b.file.pending.Done()
+			}
+		}()
+	}
+	stopProgress := im.reportProgress()
+
+	var imported sync.WaitGroup
+	var summary Summary
+	for _, path := range files {
+		if im.done[path] {
+			slog.Info("Skip imported file", "file", path)
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		file := &fileProgress{path: path}
+		summary.Files++
+		if err = im.readFile(ctx, file, batches); err != nil {
+			// file is not marked done, so it is read again on the next run
+			if ctx.Err() == nil {
+				im.reject(Reject{File: path, Index: -1, Error: err.Error()})
+			}
+			continue
+		}
+		imported.Add(1)
+		go func() {
+			defer imported.Done()
+			file.pending.Wait()
+			if ctx.Err() == nil {
+				im.markDone(file.path)
+			}
+		}()
+	}
+	close(batches)
+	workers.Wait()
+	imported.Wait()
+	stopProgress()
+
+	summary.Read, summary.Stored = im.read.Load(), im.stored.Load()
+	summary.Skipped, summary.Rejected = im.skipped.Load(), im.rejected.Load()
+	return summary, ctx.Err()
+}
+
+// readFile decodes and validates orders of file and sends them to batches.
+func (im *Importer) readFile(ctx context.Context, file *fileProgress, batches chan<- batch) error {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(file.path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	dec := json.NewDecoder(r)
+	current := batch{file: file}
+	flush := func() {
+		if len(current.orders) > 0 {
+			file.pending.Add(1)
+			batches <- current
+		}
+		current = batch{file: file}
+	}
+	index := 0
+	err = decodeOrders(ctx, dec, func(raw json.RawMessage) {
+		im.read.Add(1)
+		var order models.Order
+		err := json.Unmarshal(raw, &order)
+		if err == nil {
+			err = order.Validate()
+		}
+		if err != nil {
+			im.reject(Reject{File: file.path, Index: index, OrderId: order.OrderId, Error: err.Error(), Order: raw})
+			index++
+			return
+		}
+		current.indexes = append(current.indexes, index)
+		current.orders = append(current.orders, order)
+		current.raw = append(current.raw, raw)
+		index++
+		if len(current.orders) == im.config.BatchSize {
+			flush()
+		}
+	})
+	flush()
+	return err
+}
+
+// decodeOrders passes raw orders of array or stream of JSON values to handle.
+func decodeOrders(ctx context.Context, dec *json.Decoder, handle func(json.RawMessage)) error {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return err
+	}
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		var orders []json.RawMessage
+		if err := json.Unmarshal(raw, &orders); err != nil {
+			return err
+		}
+		for _, o := range orders {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			handle(o)
+		}
+		return nil
+	}
+	handle(raw)
+	for ctx.Err() == nil {
+		raw = nil
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		handle(raw)
+	}
+	return ctx.Err()
+}
+
+// save stores batch. If the batch fails, its orders are stored one by one to find the failing ones.
+// Batches are not rejected when import is interrupted.
+func (im *Importer) save(ctx context.Context, b batch) {
+	if ctx.Err() != nil {
+		// file of interrupted batch is not marked done, so the batch is saved on resume
+		return
+	}
+	n, err := im.Saver.ImportOrders(ctx, b.orders)
+	if err == nil {
+		im.stored.Add(int64(n))
+		im.skipped.Add(int64(len(b.orders) - n))
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if len(b.orders) == 1 {
+		for i, order := range b.orders {
+			im.reject(Reject{File: b.file.path, Index: b.indexes[i], OrderId: order.OrderId, Error: err.Error(), Order: b.raw[i]})
+		}
+		return
+	}
+	for i := range b.orders {
+		im.save(ctx, batch{file: b.file, indexes: b.indexes[i : i+1], orders: b.orders[i : i+1], raw: b.raw[i : i+1]})
+	}
+}
+
+func (im *Importer) reject(r Reject) {
+	if r.Index >= 0 {
+		im.rejected.Add(1)
+	}
+	im.rejectMu.Lock()
+	defer im.rejectMu.Unlock()
+	if err := im.rejects.Encode(r); err != nil {
+		slog.Error("Error with writing reject", "error", err)
+	}
+}
+
+func (im *Importer) reportProgress() func() {
+	ticker := time.NewTicker(im.config.ProgressInterval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				slog.Info("Import progress", "read", im.read.Load(), "stored", im.stored.Load(),
+					"skipped", im.skipped.Load(), "rejected", im.rejected.Load())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// openState reads files imported by previous runs and opens state file for appending.
+func (im *Importer) openState() error {
+	im.done = make(map[string]bool)
+	data, err := os.ReadFile(im.config.StateFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			im.done[line] = true
+		}
+	}
+	im.state, err = os.OpenFile(im.config.StateFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	return err
+}
+
+func (im *Importer) markDone(path string) {
+	im.stateMu.Lock()
+	defer im.stateMu.Unlock()
+	if _, err := fmt.Fprintln(im.state, path); err != nil {
+		slog.Error("Error with writing import state", "error", err)
+	}
+}
+
+// listFiles returns absolute paths of files and of regular files in directories, sorted by name.
+func listFiles(paths []string) ([]string, error) {
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				abs, err := filepath.Abs(p)
+				if err != nil {
+					return err
+				}
+				files = append(files, abs)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,89 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"wb-tech-backend/internal/consumer"
+	"wb-tech-backend/internal/pkg/pgdb"
+
+	"github.com/avast/retry-go/v4"
+	"github.com/segmentio/kafka-go"
+)
+
+var _ consumer.Consumer = (*Kafka)(nil)
+
+type Deps struct {
+	Reader *kafka.Reader
+	Saver  consumer.OrderSaver
+}
+
+type Kafka struct {
+	Deps
+}
+
+func NewKafka(saver consumer.OrderSaver, brokers []string, groupId, topic string) *Kafka {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		GroupID: groupId,
+		Topic:   topic,
+	})
+	return &Kafka{
+		Deps{
+			Reader: r,
+			Saver:  saver,
+		}}
+}
+
+// Run reads messages of consumer group and commits offset only after order is saved.
+// Saving is retried until it succeeds, so offsets are never committed past unsaved orders.
+// Orders rejected by storage would fail forever, they are logged and skipped.
+func (k *Kafka) Run(ctx context.Context) error {
+	for {
+		msg, err := k.Reader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+		order, err := consumer.DecodeOrder(msg.Value)
+		if err != nil {
+			log.Printf("Skip invalid message at offset %d: %v", msg.Offset, err)
+		} else {
+			err = retry.Do(func() error {
+				return k.Saver.AddOrder(ctx, order)
+			},
+				retry.Context(ctx),
+				retry.Attempts(0),
+				retry.Delay(time.Second),
+				retry.MaxDelay(30*time.Second),
+				retry.LastErrorOnly(true),
+				retry.RetryIf(func(err error) bool {
+					return !pgdb.IsPermanent(err)
+				}),
+				retry.OnRetry(func(n uint, err error) {
+					log.Printf("Error with save order %s (attempt %d): %v", order.OrderId, n+1, err)
+				}))
+			if ctx.Err() != nil {
+				// saving is retried until ctx is done
+				return nil
+			}
+			if err != nil {
+				log.Printf("Skip order %s at offset %d rejected by storage: %v", order.OrderId, msg.Offset, err)
+			}
+		}
+		if err := k.Reader.CommitMessages(ctx, msg); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (k *Kafka) Close() error {
+	return k.Reader.Close()
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// CustomerSummary is aggregated view of orders of a customer.
+type CustomerSummary struct {
+	CustomerId      string            `json:"customer_id"`
+	OrdersCount     int               `json:"orders_count"`
+	TotalSpent      map[string]int64  `json:"total_spent"`
+	FirstOrderAt    time.Time         `json:"first_order_at"`
+	LastOrderAt     time.Time         `json:"last_order_at"`
+	FavouriteBrands []BrandStat       `json:"favourite_brands"`
+	Addresses       []DeliveryAddress `json:"delivery_addresses"`
+}
+
+// BrandStat is number of items of brand ordered by customer.
+type BrandStat struct {
+	Brand string `json:"brand"`
+	Items int    `json:"items"`
+}
+
+// DeliveryAddress is address orders of customer were delivered to.
+type DeliveryAddress struct {
+	Zip         string    `json:"zip"`
+	City        string    `json:"city"`
+	Region      string    `json:"region"`
+	Address     string    `json:"address"`
+	OrdersCount int       `json:"orders_count"`
+	LastOrderAt time.Time `json:"last_order_at"`
+}
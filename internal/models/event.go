@@ -0,0 +1,21 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventOrderCreated = "order.created"
+	EventOrderUpdated = "order.updated"
+)
+
+// OutboxEvent is an order event stored in outbox until it is published.
+type OutboxEvent struct {
+	Id        int64           `json:"id"`
+	Type      string          `json:"type"`
+	OrderId   string          `json:"order_uid"`
+	Order     json.RawMessage `json:"order"`
+	CreatedAt time.Time       `json:"created_at"`
+	Attempts  int             `json:"-"`
+}
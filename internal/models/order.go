@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = validator.New()
 
 type Delivery struct {
 	Name    string `json:"name" validate:"required"`
@@ -54,4 +60,35 @@ type Order struct {
 	SmId              int       `json:"sm_id" validate:"required"`
 	DateCreated       time.Time `json:"date_created" validate:"required"`
 	OofShard          string    `json:"oof_shard" validate:"required"`
+	Seq               int64     `json:"-"`
+}
+
+// OrderFilter restricts orders by customer and delivery service. Empty fields match any order.
+type OrderFilter struct {
+	CustomerId      string `form:"customer_id"`
+	DeliveryService string `form:"delivery_service"`
+}
+
+func (f OrderFilter) Match(o Order) bool {
+	return (f.CustomerId == "" || f.CustomerId == o.CustomerId) &&
+		(f.DeliveryService == "" || f.DeliveryService == o.DeliveryService)
+}
+
+// Validate checks order and all of its nested entities against validation rules.
+func (o Order) Validate() error {
+	if err := validate.Struct(o); err != nil {
+		return err
+	}
+	if err := validate.Struct(o.Delivery); err != nil {
+		return err
+	}
+	if err := validate.Struct(o.Payment); err != nil {
+		return err
+	}
+	for _, item := range o.Items {
+		if err := validate.Struct(item); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Report periods.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// ReportDimensions are dimensions reports can be grouped by besides period.
+var ReportDimensions = []string{"currency", "provider", "bank", "region", "delivery_service"}
+
+// ReportQuery restricts orders of report by creation date and sets report grouping.
+// To is inclusive, empty Period makes single row per group for the whole date range.
+type ReportQuery struct {
+	From    time.Time `form:"from" time_format:"2006-01-02"`
+	To      time.Time `form:"to" time_format:"2006-01-02"`
+	Period  string    `form:"period"`
+	GroupBy []string  `form:"group_by"`
+}
+
+func (q ReportQuery) Validate() error {
+	switch q.Period {
+	case "", PeriodDay, PeriodWeek, PeriodMonth:
+	default:
+		return fmt.Errorf("unknown period %q", q.Period)
+	}
+	for _, g := range q.GroupBy {
+		if !validDimension(g) {
+			return fmt.Errorf("unknown group_by dimension %q", g)
+		}
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return fmt.Errorf("to must not be before from")
+	}
+	return nil
+}
+
+func validDimension(d string) bool {
+	for _, v := range ReportDimensions {
+		if v == d {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportGroup identifies row of report. Amounts in different currencies are never summed up,
+// so every report is grouped by currency.
+type ReportGroup struct {
+	Period     *time.Time        `json:"period,omitempty"`
+	Currency   string            `json:"currency"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+}
+
+type RevenueRow struct {
+	ReportGroup
+	Orders       int64 `json:"orders"`
+	Amount       int64 `json:"amount"`
+	GoodsTotal   int64 `json:"goods_total"`
+	DeliveryCost int64 `json:"delivery_cost"`
+}
+
+type TopItemRow struct {
+	ReportGroup
+	Brand    string `json:"brand"`
+	NmId     int    `json:"nm_id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Quantity int64  `json:"quantity"`
+	Revenue  int64  `json:"revenue"`
+}
+
+type DeliveryServiceRow struct {
+	ReportGroup
+	DeliveryService     string  `json:"delivery_service"`
+	Orders              int64   `json:"orders"`
+	DeliveryCost        int64   `json:"delivery_cost"`
+	AverageDeliveryCost float64 `json:"average_delivery_cost"`
+}
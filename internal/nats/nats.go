@@ -2,27 +2,30 @@ package nats
 
 import (
 	"context"
-	"encoding/json"
 	"log"
-	"sync"
+	"os"
+	"regexp"
+	"strconv"
 
-	"wb-tech-backend/internal/models"
-	"wb-tech-backend/internal/service"
+	"wb-tech-backend/internal/consumer"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/nats-io/stan.go"
 )
 
+var _ consumer.Consumer = (*Nats)(nil)
+
 type Deps struct {
 	NatsConnection stan.Conn
-	Service        *service.Service
+	Saver          consumer.OrderSaver
 }
 
 type Nats struct {
 	Deps
+	subject     string
+	durableName string
 }
 
-func NewNats(service *service.Service, clusterId, clientId, natsUrl string) (*Nats, error) {
+func NewNats(saver consumer.OrderSaver, clusterId, clientId, natsUrl, subject string) (*Nats, error) {
 	sc, err := stan.Connect(
 		clusterId,
 		clientId,
@@ -31,56 +34,65 @@ func NewNats(service *service.Service, clusterId, clientId, natsUrl string) (*Na
 		return nil, err
 	}
 	return &Nats{
-		Deps{
+		Deps: Deps{
 			NatsConnection: sc,
-			Service:        service,
-		}}, nil
+			Saver:          saver,
+		},
+		subject:     subject,
+		durableName: clientId,
+	}, nil
 }
 
-func (n *Nats) SubscribeToUpdates(wg *sync.WaitGroup, ctx context.Context, subj string) error {
-	defer wg.Done()
-	sub, err := n.NatsConnection.Subscribe(subj, func(msg *stan.Msg) {
-		var order models.Order
-		if err := json.Unmarshal(msg.Data, &order); err != nil {
-			log.Printf("Error unmarshaling message: %v", err)
-			return
-		}
-		validate := validator.New()
-		if err := validate.Struct(order); err != nil {
-			log.Printf("Validation error: %v", err)
-			return
-		}
-		if err := validate.Struct(order.Delivery); err != nil {
-			log.Printf("Validation error: %v", err)
+var invalidClientIdChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ReplicaId returns id of the running process unique among replicas, which is valid in client id.
+func ReplicaId() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	return invalidClientIdChars.ReplaceAllString(hostname, "-") + "-" + strconv.Itoa(os.Getpid()), nil
+}
+
+// NewPublisher returns nats-streaming connection for publishing messages. Replica id is appended
+// to clientId, since nats-streaming rejects connections of replicas with the same client id.
+func NewPublisher(clusterId, clientId, natsUrl string) (stan.Conn, error) {
+	replicaId, err := ReplicaId()
+	if err != nil {
+		return nil, err
+	}
+	return stan.Connect(clusterId, clientId+"-"+replicaId, stan.NatsURL(natsUrl))
+}
+
+// Run subscribes to subject and acknowledges messages only after order is saved,
+// so messages that failed to save are redelivered by nats-streaming.
+func (n *Nats) Run(ctx context.Context) error {
+	sub, err := n.NatsConnection.Subscribe(n.subject, func(msg *stan.Msg) {
+		order, err := consumer.DecodeOrder(msg.Data)
+		if err != nil {
+			log.Printf("Skip invalid message: %v", err)
+			n.ack(msg)
 			return
 		}
-		if err := validate.Struct(order.Payment); err != nil {
-			log.Printf("Validation error: %v", err)
+		if err := n.Saver.AddOrder(ctx, order); err != nil {
+			log.Printf("Error with save order %s: %v", order.OrderId, err)
 			return
 		}
-		for _, item := range order.Items {
-			if err := validate.Struct(item); err != nil {
-				log.Printf("Validation error: %v", err)
-				return
-			}
-		}
-		err := n.Service.AddOrder(ctx, order)
-		if err != nil {
-			log.Printf(err.Error())
-		}
-	})
+		n.ack(msg)
+	}, stan.DurableName(n.durableName), stan.SetManualAckMode())
 	if err != nil {
 		return err
 	}
-	for {
-		if !sub.IsValid() {
-			wg.Done()
-			break
-		}
-	}
-	err = sub.Unsubscribe()
-	if err != nil {
-		return err
+	<-ctx.Done()
+	return sub.Close()
+}
+
+func (n *Nats) Close() error {
+	return n.NatsConnection.Close()
+}
+
+func (n *Nats) ack(msg *stan.Msg) {
+	if err := msg.Ack(); err != nil {
+		log.Printf("Error with ack message: %v", err)
 	}
-	return nil
 }
@@ -0,0 +1,110 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/models"
+)
+
+type Repository interface {
+	ClaimOutbox(ctx context.Context, limit uint64, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkOutboxSent(ctx context.Context, id int64) error
+	MarkOutboxFailed(ctx context.Context, id int64, nextAttemptAt time.Time) error
+}
+
+// Publisher publishes message to subject and returns after broker acknowledged it.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+type Deps struct {
+	Repository Repository
+	Publisher  Publisher
+}
+
+// Relay publishes order events from outbox. Events are claimed in storage and published after claim is
+// committed. Event is marked as sent only after it was acknowledged by broker, so every event is delivered
+// at least once: event is published again if relay stops or storage fails before it is marked sent.
+type Relay struct {
+	Deps
+	config core.OutboxConfig
+}
+
+func NewRelay(repo Repository, publisher Publisher, cfg core.OutboxConfig) *Relay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize == 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	return &Relay{
+		Deps: Deps{
+			Repository: repo,
+			Publisher:  publisher,
+		},
+		config: cfg,
+	}
+}
+
+// Run relays pending events every configured interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := r.relayBatch(ctx)
+			if err != nil {
+				log.Printf("Error with relay outbox events: %v", err)
+				break
+			}
+			if uint64(n) < r.config.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	events, err := r.Repository.ClaimOutbox(ctx, r.config.BatchSize, r.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return 0, err
+		}
+		if err = r.Publisher.Publish(r.config.Subject, data); err != nil {
+			log.Printf("Error with publish event %d: %v", event.Id, err)
+			if err = r.Repository.MarkOutboxFailed(ctx, event.Id, time.Now().Add(r.retryDelay(event.Attempts))); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err = r.Repository.MarkOutboxSent(ctx, event.Id); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// retryDelay returns exponential backoff delay for event that failed attempts times.
+func (r *Relay) retryDelay(attempts int) time.Duration {
+	delay := r.config.RetryDelay
+	for i := 0; i < attempts && delay < r.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, r.config.MaxRetryDelay)
+}
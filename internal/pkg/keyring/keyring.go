@@ -0,0 +1,248 @@
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const keySize = 32
+
+var ErrUnknownKey = errors.New("unknown key id")
+
+// file is the keyring file format. Keys are base64 encoded 256-bit AES keys.
+type file struct {
+	Active        string            `json:"active"`
+	Keys          map[string][]byte `json:"keys"`
+	BlindIndexKey []byte            `json:"blindIndexKey"`
+}
+
+// Keyring holds key encryption keys used for envelope encryption and the key of blind indexes.
+// Data is encrypted with random per-record data key, which is stored wrapped with the active key.
+type Keyring struct {
+	active        string
+	keys          map[string]cipher.AEAD
+	blindIndexKey []byte
+}
+
+// Load reads keyring from file.
+func Load(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse keyring: %w", err)
+	}
+	if _, ok := f.Keys[f.Active]; !ok {
+		return nil, fmt.Errorf("active key %q is not in keyring", f.Active)
+	}
+	if len(f.BlindIndexKey) < keySize {
+		return nil, fmt.Errorf("blind index key must be at least %d bytes", keySize)
+	}
+	k := &Keyring{
+		active:        f.Active,
+		keys:          make(map[string]cipher.AEAD, len(f.Keys)),
+		blindIndexKey: f.BlindIndexKey,
+	}
+	for id, key := range f.Keys {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes", id, keySize)
+		}
+		if k.keys[id], err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// AddKey adds new random key to keyring file and makes it active. The file is created if it does not exist.
+func AddKey(path string) (string, error) {
+	f := file{Keys: make(map[string][]byte)}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err = json.Unmarshal(data, &f); err != nil {
+			return "", fmt.Errorf("parse keyring: %w", err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return "", err
+	}
+	if f.Keys == nil {
+		f.Keys = make(map[string][]byte)
+	}
+	if len(f.BlindIndexKey) == 0 {
+		if f.BlindIndexKey, err = randomBytes(keySize); err != nil {
+			return "", err
+		}
+	}
+	id := time.Now().UTC().Format("20060102T150405Z")
+	if _, ok := f.Keys[id]; ok {
+		return "", fmt.Errorf("key %q already exists", id)
+	}
+	if f.Keys[id], err = randomBytes(keySize); err != nil {
+		return "", err
+	}
+	f.Active = id
+	data, err = json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return id, os.WriteFile(path, data, 0o600)
+}
+
+// ActiveKeyId returns id of the key used to wrap new data keys.
+func (k *Keyring) ActiveKeyId() string {
+	return k.active
+}
+
+// NewDataKey returns random data key wrapped with the active key.
+func (k *Keyring) NewDataKey() (DataKey, error) {
+	key, err := randomBytes(keySize)
+	if err != nil {
+		return DataKey{}, err
+	}
+	wrapped, err := seal(k.keys[k.active], key, []byte(k.active))
+	if err != nil {
+		return DataKey{}, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return DataKey{}, err
+	}
+	return DataKey{KeyId: k.active, Wrapped: wrapped, aead: aead}, nil
+}
+
+// OpenDataKey unwraps data key wrapped with key keyId.
+func (k *Keyring) OpenDataKey(keyId string, wrapped []byte) (DataKey, error) {
+	kek, ok := k.keys[keyId]
+	if !ok {
+		return DataKey{}, fmt.Errorf("%w %q", ErrUnknownKey, keyId)
+	}
+	key, err := open(kek, wrapped, []byte(keyId))
+	if err != nil {
+		return DataKey{}, fmt.Errorf("unwrap data key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return DataKey{}, err
+	}
+	return DataKey{KeyId: keyId, Wrapped: wrapped, aead: aead}, nil
+}
+
+// Seal encrypts plaintext of field with a new data key and returns self-contained envelope:
+// length-prefixed key id and wrapped data key followed by ciphertext.
+func (k *Keyring) Seal(field string, plaintext []byte) ([]byte, error) {
+	dk, err := k.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(dk.aead, plaintext, []byte(field))
+	if err != nil {
+		return nil, err
+	}
+	envelope := make([]byte, 0, 4+len(dk.KeyId)+len(dk.Wrapped)+len(ciphertext))
+	envelope = binary.BigEndian.AppendUint16(envelope, uint16(len(dk.KeyId)))
+	envelope = append(envelope, dk.KeyId...)
+	envelope = binary.BigEndian.AppendUint16(envelope, uint16(len(dk.Wrapped)))
+	envelope = append(envelope, dk.Wrapped...)
+	return append(envelope, ciphertext...), nil
+}
+
+// Open decrypts envelope made by Seal.
+func (k *Keyring) Open(field string, envelope []byte) ([]byte, error) {
+	keyId, rest, err := splitPrefixed(envelope)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, ciphertext, err := splitPrefixed(rest)
+	if err != nil {
+		return nil, err
+	}
+	dk, err := k.OpenDataKey(string(keyId), wrapped)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(dk.aead, ciphertext, []byte(field))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", field, err)
+	}
+	return plaintext, nil
+}
+
+func splitPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 2 || len(data) < 2+int(binary.BigEndian.Uint16(data)) {
+		return nil, nil, errors.New("envelope is too short")
+	}
+	n := 2 + int(binary.BigEndian.Uint16(data))
+	return data[2:n], data[n:], nil
+}
+
+// BlindIndex returns keyed hash of value allowing to search by value without storing it in plain text.
+func (k *Keyring) BlindIndex(value string) []byte {
+	mac := hmac.New(sha256.New, k.blindIndexKey)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// DataKey encrypts fields of a single record.
+type DataKey struct {
+	KeyId   string
+	Wrapped []byte
+	aead    cipher.AEAD
+}
+
+// Encrypt encrypts value of field. Field name is authenticated, so ciphertext can't be moved to another field.
+func (d DataKey) Encrypt(field, value string) ([]byte, error) {
+	return seal(d.aead, []byte(value), []byte(field))
+}
+
+func (d DataKey) Decrypt(field string, ciphertext []byte) (string, error) {
+	value, err := open(d.aead, ciphertext, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", field, err)
+	}
+	return string(value), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and prepends random nonce to ciphertext.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce, err := randomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
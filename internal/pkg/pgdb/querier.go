@@ -11,15 +11,17 @@ import (
 type txCtxKey struct{}
 
 // NewQueryManager returns new *QueryManager.
-func NewQueryManager(pool *pgxpool.Pool) *QueryManager {
-	return &QueryManager{Pool: pool}
+func NewQueryManager(pool *pgxpool.Pool, timeouts Timeouts) *QueryManager {
+	return &QueryManager{Pool: pool, timeouts: timeouts}
 }
 
+// QueryManager executes statements limiting each of them by read or write timeout.
 type QueryManager struct {
-	Pool *pgxpool.Pool
+	Pool     *pgxpool.Pool
+	timeouts Timeouts
 }
 
-// QuerySq executes query with squirrel.
+// QuerySq executes query with squirrel. Returned rows must be closed to release query timeout.
 func (qm *QueryManager) QuerySq(ctx context.Context, query sq.Sqlizer) (pgx.Rows, error) {
 	tx, withTransaction := transactionFromContext(ctx)
 
@@ -28,10 +30,39 @@ func (qm *QueryManager) QuerySq(ctx context.Context, query sq.Sqlizer) (pgx.Rows
 		return nil, err
 	}
 
+	op := statementOp(querySql)
+	opCtx, cancel := qm.timeouts.withTimeout(ctx, op)
+	var rows pgx.Rows
 	if withTransaction {
-		return tx.Query(ctx, querySql, args...)
+		rows, err = tx.Query(opCtx, querySql, args...)
+	} else {
+		rows, err = qm.Pool.Query(opCtx, querySql, args...)
 	}
-	return qm.Pool.Query(ctx, querySql, args...)
+	if err != nil {
+		cancel()
+		return nil, qm.timeouts.wrap(ctx, opCtx, op, err)
+	}
+	return &timedRows{Rows: rows, parent: ctx, ctx: opCtx, cancel: cancel, op: op, timeouts: qm.timeouts}, nil
+}
+
+// ExecSq executes query with squirrel and returns number of affected rows.
+func (qm *QueryManager) ExecSq(ctx context.Context, query sq.Sqlizer) (int64, error) {
+	tx, withTransaction := transactionFromContext(ctx)
+
+	querySql, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	op := statementOp(querySql)
+	opCtx, cancel := qm.timeouts.withTimeout(ctx, op)
+	defer cancel()
+	if withTransaction {
+		tag, err := tx.Exec(opCtx, querySql, args...)
+		return tag.RowsAffected(), qm.timeouts.wrap(ctx, opCtx, op, err)
+	}
+	tag, err := qm.Pool.Exec(opCtx, querySql, args...)
+	return tag.RowsAffected(), qm.timeouts.wrap(ctx, opCtx, op, err)
 }
 
 func transactionFromContext(ctx context.Context) (pgx.Tx, bool) {
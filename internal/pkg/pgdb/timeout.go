@@ -0,0 +1,143 @@
+package pgdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrTimeout matches errors of operations cancelled by timeout.
+var ErrTimeout = errors.New("storage operation timed out")
+
+// codeQueryCanceled is SQLSTATE of statement cancelled by server, e.g. by statement_timeout.
+const codeQueryCanceled = "57014"
+
+const (
+	opRead   = "read"
+	opWrite  = "write"
+	opBegin  = "begin"
+	opCommit = "commit"
+)
+
+var cancelledStatements = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pgdb_cancelled_statements_total",
+	Help: "Number of storage operations cancelled by timeout or by caller.",
+}, []string{"operation", "reason"})
+
+// Timeouts limit duration of single storage operations, operations are not limited if timeout is zero.
+type Timeouts struct {
+	// Read limits statements reading data: SELECT and FETCH. Rows must be read within the timeout.
+	Read time.Duration
+	// Write limits other statements and beginning and committing transactions.
+	Write time.Duration
+}
+
+// TimeoutError is returned when storage operation is cancelled by timeout.
+type TimeoutError struct {
+	Operation string
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("storage %s timed out after %s: %v", e.Operation, e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+func (t Timeouts) of(op string) time.Duration {
+	if op == opRead {
+		return t.Read
+	}
+	return t.Write
+}
+
+// withTimeout returns context limited by timeout of operation.
+func (t Timeouts) withTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	if timeout := t.of(op); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// wrap converts error of operation executed with opCtx derived from ctx to TimeoutError if it was
+// cancelled by timeout and counts operations cancelled by timeout or by caller.
+func (t Timeouts) wrap(ctx, opCtx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(opCtx.Err(), context.DeadlineExceeded), errors.As(err, &pgErr) && pgErr.Code == codeQueryCanceled:
+		cancelledStatements.WithLabelValues(op, "timeout").Inc()
+		return &TimeoutError{Operation: op, Timeout: t.of(op), Err: err}
+	case ctx.Err() != nil:
+		cancelledStatements.WithLabelValues(op, "canceled").Inc()
+	}
+	return err
+}
+
+// dataModifyingVerbs are verbs of statements in WITH clause that make the whole statement a write.
+var dataModifyingVerbs = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE)\b`)
+
+// statementOp returns operation of SQL statement. Statement with WITH clause is a read unless it
+// contains data-modifying statement, SELECT ... FOR UPDATE in WITH clause is treated as a write too.
+func statementOp(sql string) string {
+	sql = strings.TrimSpace(sql)
+	verb := sql
+	if i := strings.IndexFunc(sql, func(r rune) bool { return !unicode.IsLetter(r) }); i >= 0 {
+		verb = sql[:i]
+	}
+	switch strings.ToUpper(verb) {
+	case "SELECT", "FETCH", "VALUES", "TABLE":
+		return opRead
+	case "WITH":
+		if !dataModifyingVerbs.MatchString(sql) {
+			return opRead
+		}
+	}
+	return opWrite
+}
+
+// timedRows releases operation context when rows are read or closed and reports timeout of reading rows.
+type timedRows struct {
+	pgx.Rows
+	parent   context.Context
+	ctx      context.Context
+	cancel   context.CancelFunc
+	op       string
+	timeouts Timeouts
+}
+
+// Next releases operation context after the last row, since rows are closed by then.
+func (r *timedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.cancel()
+	return false
+}
+
+func (r *timedRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+func (r *timedRows) Err() error {
+	return r.timeouts.wrap(r.parent, r.ctx, r.op, r.Rows.Err())
+}
@@ -2,55 +2,210 @@ package pgdb
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	// classConnectionException is SQLSTATE class of connection errors.
+	classConnectionException = "08"
+	// classDataException and classIntegrityViolation are SQLSTATE classes of errors caused by stored data.
+	classDataException      = "22"
+	classIntegrityViolation = "23"
+)
+
+var transactionRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pgdb_transaction_retries_total",
+	Help: "Number of transactions retried after retryable error.",
+}, []string{"reason"})
+
+// TxConfig represents configuration of transactions.
+type TxConfig struct {
+	// IsoLevel of transactions, server default is used if empty.
+	IsoLevel pgx.TxIsoLevel
+	// Retries is the maximum number of retries of transaction failed with retryable error.
+	Retries    int
+	RetryDelay time.Duration
+	// MaxRetryDelay limits exponential retry delay, it is not limited if zero.
+	MaxRetryDelay time.Duration
+}
+
+// ParseIsoLevel returns isolation level by its name, e.g. "repeatable read".
+func ParseIsoLevel(name string) (pgx.TxIsoLevel, error) {
+	switch level := pgx.TxIsoLevel(strings.ToLower(name)); level {
+	case "", pgx.ReadCommitted, pgx.RepeatableRead, pgx.Serializable:
+		return level, nil
+	default:
+		return "", fmt.Errorf("unknown isolation level %q", name)
+	}
+}
+
 type TransactionManager struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	timeouts Timeouts
+	config   TxConfig
 }
 
-func NewTransactionManager(pool *pgxpool.Pool) *TransactionManager {
-	return &TransactionManager{pool: pool}
+func NewTransactionManager(pool *pgxpool.Pool, timeouts Timeouts, config TxConfig) *TransactionManager {
+	return &TransactionManager{pool: pool, timeouts: timeouts, config: config}
 }
 
 // TransactionCallback represents function that will be executed withing single db transaction
 type TransactionCallback func(ctx context.Context) error
 
-// ReadonlyTx makes transaction reading without commit.
+// ReadonlyTx executes a callback within a single read-only transaction. Transaction is not retried,
+// since callback may pass read data further. Within transaction of ctx callback is executed in it.
 func (tm *TransactionManager) ReadonlyTx(ctx context.Context, callback TransactionCallback) error {
-	tx, err := tm.pool.Begin(ctx)
+	if _, ok := transactionFromContext(ctx); ok {
+		return callback(ctx)
+	}
+	return tm.tx(ctx, pgx.TxOptions{IsoLevel: tm.config.IsoLevel, AccessMode: pgx.ReadOnly}, callback)
+}
+
+// Tx executes a callback within a single transaction. Statements of callback are limited by their
+// timeouts, beginning and committing transaction are limited by write timeout.
+//
+// Transaction failed with serialization failure, deadlock or lost connection is retried with exponential
+// delay, so callback may be executed several times and must not keep state of failed attempts.
+// Within transaction of ctx callback is executed in nested transaction implemented with savepoint,
+// which is not retried by itself.
+func (tm *TransactionManager) Tx(ctx context.Context, callback TransactionCallback) error {
+	if tx, ok := transactionFromContext(ctx); ok {
+		return tm.nested(ctx, tx, callback)
+	}
+	delay := tm.config.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := tm.tx(ctx, pgx.TxOptions{IsoLevel: tm.config.IsoLevel}, callback)
+		reason := retryReason(err)
+		if err == nil || reason == "" || attempt > tm.config.Retries {
+			return err
+		}
+		transactionRetries.WithLabelValues(reason).Inc()
+		slog.Warn("Retrying transaction", "attempt", attempt, "reason", reason, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if tm.config.MaxRetryDelay > 0 {
+			delay = min(delay, tm.config.MaxRetryDelay)
+		}
+	}
+}
+
+func (tm *TransactionManager) tx(ctx context.Context, options pgx.TxOptions, callback TransactionCallback) error {
+	opCtx, cancel := tm.timeouts.withTimeout(ctx, opBegin)
+	tx, err := tm.pool.BeginTx(opCtx, options)
+	err = tm.timeouts.wrap(ctx, opCtx, opBegin, err)
+	cancel()
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = tx.Rollback(ctx)
-	}()
-
-	ctx = context.WithValue(ctx, txCtxKey{}, tx)
+	defer tm.rollback(ctx, tx)
 
-	if err := callback(ctx); err != nil {
+	if err = callback(context.WithValue(ctx, txCtxKey{}, tx)); err != nil {
 		return err
 	}
+	if options.AccessMode == pgx.ReadOnly {
+		return nil
+	}
 
+	opCtx, cancel = tm.timeouts.withTimeout(ctx, opCommit)
+	defer cancel()
+	if err = tm.timeouts.wrap(ctx, opCtx, opCommit, tx.Commit(opCtx)); err != nil {
+		return &commitError{err: err}
+	}
 	return nil
 }
 
-// Tx executes a callback within a single transaction.
-func (tm *TransactionManager) Tx(ctx context.Context, callback TransactionCallback) error {
-	tx, err := tm.pool.Begin(ctx)
+// nested executes callback within savepoint of tx.
+func (tm *TransactionManager) nested(ctx context.Context, tx pgx.Tx, callback TransactionCallback) error {
+	opCtx, cancel := tm.timeouts.withTimeout(ctx, opBegin)
+	savepoint, err := tx.Begin(opCtx)
+	err = tm.timeouts.wrap(ctx, opCtx, opBegin, err)
+	cancel()
 	if err != nil {
 		return err
 	}
-	defer func() {
-		_ = tx.Rollback(ctx)
-	}()
-
-	ctx = context.WithValue(ctx, txCtxKey{}, tx)
+	defer tm.rollback(ctx, savepoint)
 
-	if err = callback(ctx); err != nil {
+	if err = callback(context.WithValue(ctx, txCtxKey{}, savepoint)); err != nil {
 		return err
 	}
 
-	return tx.Commit(ctx)
+	opCtx, cancel = tm.timeouts.withTimeout(ctx, opCommit)
+	defer cancel()
+	return tm.timeouts.wrap(ctx, opCtx, opCommit, savepoint.Commit(opCtx))
+}
+
+// rollback rolls back transaction unless it is committed. Rollback is not cancelled with ctx,
+// so connection is returned to pool in a clean state.
+func (tm *TransactionManager) rollback(ctx context.Context, tx pgx.Tx) {
+	opCtx, cancel := tm.timeouts.withTimeout(context.WithoutCancel(ctx), opWrite)
+	defer cancel()
+	_ = tx.Rollback(opCtx)
+}
+
+// commitError is error of commit. Commit that lost connection may have succeeded, so it is not retried.
+type commitError struct {
+	err error
+}
+
+func (e *commitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *commitError) Unwrap() error {
+	return e.err
+}
+
+// IsPermanent reports whether err is caused by data that is rejected by storage, so statement fails
+// the same way however many times it is retried.
+func IsPermanent(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return strings.HasPrefix(pgErr.Code, classDataException) || strings.HasPrefix(pgErr.Code, classIntegrityViolation)
+}
+
+// retryReason returns reason to retry transaction failed with err or empty string if it must not be retried.
+func retryReason(err error) string {
+	if err == nil || errors.Is(err, ErrTimeout) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ""
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == codeSerializationFailure:
+			return "serialization_failure"
+		case pgErr.Code == codeDeadlockDetected:
+			return "deadlock"
+		case !strings.HasPrefix(pgErr.Code, classConnectionException):
+			return ""
+		}
+	}
+	var commitErr *commitError
+	if errors.As(err, &commitErr) {
+		return ""
+	}
+	var netErr net.Error
+	if pgErr != nil || pgconn.SafeToRetry(err) || errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return "connection"
+	}
+	return ""
 }
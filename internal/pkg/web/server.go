@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // ServerConfig represents configuration for Server.
@@ -27,6 +28,7 @@ type Server interface {
 	Shutdown(ctx context.Context) error
 	Router() gin.IRouter
 	Ready() bool
+	SetReady(ready bool)
 }
 
 var _ Server = (*BaseServer)(nil)
@@ -63,6 +65,7 @@ func NewServer(config ServerConfig, handler *gin.Engine) *BaseServer {
 func (s *BaseServer) Run(ctx context.Context) error {
 	s.Router().GET("/live", func(_ *gin.Context) {})
 	s.Router().GET("/ping", s.getPing)
+	s.Router().GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	go func() {
 		for {
@@ -89,6 +92,15 @@ func (s *BaseServer) Ready() bool {
 	return atomic.LoadInt32(&s.isNotReady) == 0
 }
 
+// SetReady sets whether server can accept requests, which is reported by /ping.
+func (s *BaseServer) SetReady(ready bool) {
+	var notReady int32
+	if !ready {
+		notReady = 1
+	}
+	atomic.StoreInt32(&s.isNotReady, notReady)
+}
+
 func (s *BaseServer) getPing(ctx *gin.Context) {
 	if s.Ready() {
 		_, _ = ctx.Writer.Write([]byte("pong"))
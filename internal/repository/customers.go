@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"wb-tech-backend/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// favouriteBrandsLimit is the number of brands in customer summary.
+const favouriteBrandsLimit = 5
+
+// GetCustomerOrders returns up to limit orders of customer newest first, skipping offset orders.
+func (r *Repository) GetCustomerOrders(ctx context.Context, customerId string, limit, offset uint64) ([]models.Order, error) {
+	query := ordersQuery().Where(sq.Eq{"o.customer_id": customerId}).OrderBy("o.seq DESC").Limit(limit).Offset(offset)
+	return r.queryOrders(ctx, query)
+}
+
+// GetCustomerSummary returns aggregated view of customer orders. Summary of customer without orders has zero OrdersCount.
+func (r *Repository) GetCustomerSummary(ctx context.Context, customerId string) (models.CustomerSummary, error) {
+	summary := models.CustomerSummary{
+		CustomerId:      customerId,
+		TotalSpent:      make(map[string]int64),
+		FavouriteBrands: make([]models.BrandStat, 0),
+		Addresses:       make([]models.DeliveryAddress, 0),
+	}
+	if err := r.customerOrdersStats(ctx, &summary); err != nil || summary.OrdersCount == 0 {
+		return summary, err
+	}
+	if err := r.customerSpending(ctx, &summary); err != nil {
+		return summary, err
+	}
+	if err := r.customerBrands(ctx, &summary); err != nil {
+		return summary, err
+	}
+	return summary, r.customerAddresses(ctx, &summary)
+}
+
+func (r *Repository) customerOrdersStats(ctx context.Context, summary *models.CustomerSummary) error {
+	query := sq.Select("count(*)", "coalesce(min(date_created), 'epoch')", "coalesce(max(date_created), 'epoch')").
+		From("orders").Where(sq.Eq{"customer_id": summary.CustomerId}).PlaceholderFormat(sq.Dollar)
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		if err = rows.Scan(&summary.OrdersCount, &summary.FirstOrderAt, &summary.LastOrderAt); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *Repository) customerSpending(ctx context.Context, summary *models.CustomerSummary) error {
+	query := sq.Select("p.currency", "sum(p.amount)").From("orders o").
+		Join("payments p ON o.payment_id = p.payment_id").
+		Where(sq.Eq{"o.customer_id": summary.CustomerId}).GroupBy("p.currency").PlaceholderFormat(sq.Dollar)
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var currency string
+		var total int64
+		if err = rows.Scan(&currency, &total); err != nil {
+			return err
+		}
+		summary.TotalSpent[currency] = total
+	}
+	return rows.Err()
+}
+
+func (r *Repository) customerBrands(ctx context.Context, summary *models.CustomerSummary) error {
+	query := sq.Select("i.brand", "count(*) AS items").From("orders o").
+		Join("items i ON i.item_id = ANY(o.items_ids)").
+		Where(sq.Eq{"o.customer_id": summary.CustomerId}).GroupBy("i.brand").
+		OrderBy("items DESC", "i.brand").Limit(favouriteBrandsLimit).PlaceholderFormat(sq.Dollar)
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var brand models.BrandStat
+		if err = rows.Scan(&brand.Brand, &brand.Items); err != nil {
+			return err
+		}
+		summary.FavouriteBrands = append(summary.FavouriteBrands, brand)
+	}
+	return rows.Err()
+}
+
+// customerAddresses groups orders of customer by delivery address. Addresses may be encrypted with
+// random data keys, so they are decrypted and grouped here rather than by SQL.
+func (r *Repository) customerAddresses(ctx context.Context, summary *models.CustomerSummary) error {
+	columns := append([]string{"d.zip", "d.city", "d.region", "o.date_created"}, deliveryPIIColumns...)
+	query := sq.Select(columns...).From("orders o").
+		Join("deliveries d ON o.delivery_id = d.delivery_id").
+		Where(sq.Eq{"o.customer_id": summary.CustomerId}).PlaceholderFormat(sq.Dollar)
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	addresses := make(map[models.DeliveryAddress]*models.DeliveryAddress)
+	for rows.Next() {
+		var d models.Delivery
+		var sealed sealedDelivery
+		var dateCreated time.Time
+		if err = rows.Scan(append([]interface{}{&d.Zip, &d.City, &d.Region, &dateCreated}, sealed.scanDest()...)...); err != nil {
+			return err
+		}
+		if err = r.openDelivery(sealed, &d); err != nil {
+			return err
+		}
+		key := models.DeliveryAddress{Zip: d.Zip, City: d.City, Region: d.Region, Address: d.Address}
+		a, ok := addresses[key]
+		if !ok {
+			a = &key
+			addresses[key] = a
+		}
+		a.OrdersCount++
+		if dateCreated.After(a.LastOrderAt) {
+			a.LastOrderAt = dateCreated
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for _, a := range addresses {
+		summary.Addresses = append(summary.Addresses, *a)
+	}
+	sort.Slice(summary.Addresses, func(i, j int) bool {
+		return summary.Addresses[i].LastOrderAt.After(summary.Addresses[j].LastOrderAt)
+	})
+	return nil
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"wb-tech-backend/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var errNoKeyring = errors.New("delivery is encrypted but keyring is not configured")
+
+// deliveryPIIColumns are columns holding delivery personal data either in plain text
+// or encrypted with data key wrapped by keyring key key_id.
+var deliveryPIIColumns = []string{
+	"d.name", "d.phone", "d.address", "d.email",
+	"d.key_id", "d.wrapped_key", "d.name_enc", "d.phone_enc", "d.address_enc", "d.email_enc",
+}
+
+// sealedDelivery is delivery personal data as it is stored in deliveries table.
+type sealedDelivery struct {
+	Name, Phone, Address, Email             *string
+	KeyId                                   *string
+	WrappedKey                              []byte
+	NameEnc, PhoneEnc, AddressEnc, EmailEnc []byte
+}
+
+func (s *sealedDelivery) scanDest() []interface{} {
+	return []interface{}{
+		&s.Name, &s.Phone, &s.Address, &s.Email,
+		&s.KeyId, &s.WrappedKey, &s.NameEnc, &s.PhoneEnc, &s.AddressEnc, &s.EmailEnc,
+	}
+}
+
+// sealDelivery returns values of delivery personal data columns. Data is encrypted if keyring is
+// configured, otherwise it is stored in plain text.
+func (r *Repository) sealDelivery(d models.Delivery) (map[string]interface{}, error) {
+	if r.Keyring == nil {
+		return plainDelivery(d)
+	}
+	dk, err := r.Keyring.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]interface{}{
+		"name": nil, "phone": nil, "address": nil, "email": nil,
+		"key_id": dk.KeyId, "wrapped_key": dk.Wrapped,
+		"phone_bidx": r.Keyring.BlindIndex(normalizePhone(d.Phone)),
+		"email_bidx": r.Keyring.BlindIndex(normalizeEmail(d.Email)),
+	}
+	for column, value := range map[string]string{"name": d.Name, "phone": d.Phone, "address": d.Address, "email": d.Email} {
+		if columns[column+"_enc"], err = dk.Encrypt(column, value); err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
+
+// plainDelivery returns values of delivery personal data columns stored in plain text.
+func plainDelivery(d models.Delivery) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"name": d.Name, "phone": d.Phone, "address": d.Address, "email": d.Email,
+		"key_id": nil, "wrapped_key": nil, "name_enc": nil, "phone_enc": nil, "address_enc": nil, "email_enc": nil,
+		"phone_bidx": nil, "email_bidx": nil,
+	}, nil
+}
+
+// openDelivery fills delivery personal data from stored columns.
+func (r *Repository) openDelivery(s sealedDelivery, d *models.Delivery) error {
+	if s.KeyId == nil {
+		d.Name, d.Phone, d.Address, d.Email = deref(s.Name), deref(s.Phone), deref(s.Address), deref(s.Email)
+		return nil
+	}
+	if r.Keyring == nil {
+		return errNoKeyring
+	}
+	dk, err := r.Keyring.OpenDataKey(*s.KeyId, s.WrappedKey)
+	if err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		column string
+		value  []byte
+		dst    *string
+	}{
+		{"name", s.NameEnc, &d.Name},
+		{"phone", s.PhoneEnc, &d.Phone},
+		{"address", s.AddressEnc, &d.Address},
+		{"email", s.EmailEnc, &d.Email},
+	} {
+		if *f.dst, err = dk.Decrypt(f.column, f.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetOrdersByContact returns orders which delivery has given email or phone. Encrypted deliveries
+// are found by blind index, deliveries stored before encryption was enabled by plain values. Both are
+// compared normalized: email case and surrounding spaces and phone non-digits are ignored.
+func (r *Repository) GetOrdersByContact(ctx context.Context, email, phone string) ([]models.Order, error) {
+	cond := sq.Or{}
+	// plain text contacts are compared normalized like blind indexes, expressions match indexes
+	if email = normalizeEmail(email); email != "" {
+		cond = append(cond, sq.Expr("lower(trim(d.email)) = ?", email))
+		if r.Keyring != nil {
+			// []byte must not be passed to sq.Eq, it would be expanded to IN list
+			cond = append(cond, sq.Expr("d.email_bidx = ?", r.Keyring.BlindIndex(email)))
+		}
+	}
+	if phone = normalizePhone(phone); phone != "" {
+		cond = append(cond, sq.Expr("regexp_replace(d.phone, '[^0-9]', '', 'g') = ?", phone))
+		if r.Keyring != nil {
+			cond = append(cond, sq.Expr("d.phone_bidx = ?", r.Keyring.BlindIndex(phone)))
+		}
+	}
+	if len(cond) == 0 {
+		return []models.Order{}, nil
+	}
+	return r.queryOrders(ctx, ordersQuery().Where(cond).OrderBy("o.seq DESC"))
+}
+
+// ReencryptDeliveries encrypts up to limit deliveries stored in plain text or with non-active key
+// with the active key and returns number of processed deliveries.
+func (r *Repository) ReencryptDeliveries(ctx context.Context, limit uint64) (int, error) {
+	if r.Keyring == nil {
+		return 0, errors.New("keyring is not configured")
+	}
+	cond := sq.Or{sq.Eq{"d.key_id": nil}, sq.NotEq{"d.key_id": r.Keyring.ActiveKeyId()}}
+	n, err := r.rewriteDeliveries(ctx, cond, limit, r.sealDelivery)
+	if n > 0 {
+		slog.Info("Deliveries re-encrypted", "count", n, "key_id", r.Keyring.ActiveKeyId())
+	}
+	return n, err
+}
+
+// DecryptDeliveries stores up to limit encrypted deliveries in plain text and returns number of processed
+// deliveries. It is needed before encryption migration is rolled back.
+func (r *Repository) DecryptDeliveries(ctx context.Context, limit uint64) (int, error) {
+	if r.Keyring == nil {
+		return 0, errors.New("keyring is not configured")
+	}
+	n, err := r.rewriteDeliveries(ctx, sq.NotEq{"d.key_id": nil}, limit, plainDelivery)
+	if n > 0 {
+		slog.Info("Deliveries decrypted", "count", n)
+	}
+	return n, err
+}
+
+// rewriteDeliveries replaces personal data columns of up to limit deliveries matching cond with columns
+// returned by store for decrypted delivery.
+func (r *Repository) rewriteDeliveries(ctx context.Context, cond sq.Sqlizer, limit uint64,
+	store func(models.Delivery) (map[string]interface{}, error)) (int, error) {
+	var n int
+	err := r.TransactionManager.Tx(ctx, func(ctx context.Context) error {
+		// content of orders doesn't change, so caches don't need to be notified
+		if _, err := r.QueryManager.ExecSq(ctx, sq.Expr("SET LOCAL app.skip_order_notify = 'on'")); err != nil {
+			return err
+		}
+		columns := append([]string{"d.delivery_id"}, deliveryPIIColumns...)
+		query := sq.Select(columns...).From("deliveries d").Where(cond).
+			OrderBy("d.delivery_id").Limit(limit).Suffix("FOR UPDATE SKIP LOCKED").PlaceholderFormat(sq.Dollar)
+		rows, err := r.QueryManager.QuerySq(ctx, query)
+		if err != nil {
+			return err
+		}
+		type delivery struct {
+			id     int64
+			sealed sealedDelivery
+		}
+		deliveries := make([]delivery, 0)
+		for rows.Next() {
+			var d delivery
+			if err = rows.Scan(append([]interface{}{&d.id}, d.sealed.scanDest()...)...); err != nil {
+				rows.Close()
+				return err
+			}
+			deliveries = append(deliveries, d)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		for _, d := range deliveries {
+			var plain models.Delivery
+			if err = r.openDelivery(d.sealed, &plain); err != nil {
+				return fmt.Errorf("delivery %d: %w", d.id, err)
+			}
+			columns, err := store(plain)
+			if err != nil {
+				return err
+			}
+			_, err = r.QueryManager.ExecSq(ctx, sq.Update("deliveries").SetMap(columns).
+				Where(sq.Eq{"delivery_id": d.id}).PlaceholderFormat(sq.Dollar))
+			if err != nil {
+				return err
+			}
+		}
+		n = len(deliveries)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// normalizePhone keeps only digits of phone, so +7 (999) 123-45-67 and 79991234567 are equal.
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
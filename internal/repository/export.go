@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"wb-tech-backend/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const exportCursor = "export_orders"
+
+// ExportOrders passes orders matching filter to handle in ingestion order by batches of batchSize.
+// Orders are read from server-side cursor, so only one batch is held in memory.
+func (r *Repository) ExportOrders(ctx context.Context, filter models.OrderFilter, batchSize int, handle func([]models.Order) error) error {
+	return r.fetchOrders(ctx, exportCursor, filterOrders(ordersQuery(), filter).OrderBy("o.seq"), batchSize, handle)
+}
+
+// fetchOrders passes orders selected by query built by ordersQuery to handle by batches of batchSize,
+// reading them from server-side cursor with given name in read-only transaction.
+func (r *Repository) fetchOrders(ctx context.Context, cursor string, query sq.SelectBuilder, batchSize int, handle func([]models.Order) error) error {
+	return r.TransactionManager.ReadonlyTx(ctx, func(ctx context.Context) error {
+		querySql, args, err := query.ToSql()
+		if err != nil {
+			return err
+		}
+		_, err = r.QueryManager.ExecSq(ctx, sq.Expr("DECLARE "+cursor+" NO SCROLL CURSOR FOR "+querySql, args...))
+		if err != nil {
+			return err
+		}
+		fetch := sq.Expr(fmt.Sprintf("FETCH FORWARD %d FROM %s", batchSize, cursor))
+		for {
+			records, err := r.queryOrderRecords(ctx, fetch)
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				return nil
+			}
+			orders, err := r.withItems(ctx, records)
+			if err != nil {
+				return err
+			}
+			if err = handle(orders); err != nil {
+				return err
+			}
+		}
+	})
+}
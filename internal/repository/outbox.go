@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"wb-tech-backend/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func (r *Repository) addOutboxEvent(ctx context.Context, eventType string, order models.Order) error {
+	payload, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	query := sq.Insert("outbox").
+		Columns("event_type", "order_uid", "payload").
+		Values(eventType, order.OrderId, payload).
+		PlaceholderFormat(sq.Dollar)
+	_, err = r.QueryManager.ExecSq(ctx, query)
+	return err
+}
+
+// ClaimOutbox returns up to limit pending outbox events ready to be sent, ordered by id, and postpones their next
+// attempt by lease, so other relays skip them while they are published outside of transaction.
+// Events that are neither marked sent nor failed before lease expires are claimed again.
+func (r *Repository) ClaimOutbox(ctx context.Context, limit uint64, lease time.Duration) ([]models.OutboxEvent, error) {
+	pending, args, err := sq.Select("id").From("outbox").
+		Where("sent_at IS NULL AND next_attempt_at <= now()").
+		OrderBy("id").Limit(limit).Suffix("FOR UPDATE SKIP LOCKED").ToSql()
+	if err != nil {
+		return nil, err
+	}
+	query := sq.Update("outbox").
+		Set("next_attempt_at", sq.Expr("now() + make_interval(secs => ?)", lease.Seconds())).
+		Where(sq.Expr("id IN ("+pending+")", args...)).
+		Suffix("RETURNING id, event_type, order_uid, payload, created_at, attempts").
+		PlaceholderFormat(sq.Dollar)
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := make([]models.OutboxEvent, 0)
+	for rows.Next() {
+		var event models.OutboxEvent
+		err = rows.Scan(&event.Id, &event.Type, &event.OrderId, &event.Order, &event.CreatedAt, &event.Attempts)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING doesn't keep order of subquery
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Id < events[j].Id
+	})
+	return events, nil
+}
+
+// MarkOutboxSent marks outbox event as published.
+func (r *Repository) MarkOutboxSent(ctx context.Context, id int64) error {
+	query := sq.Update("outbox").Set("sent_at", sq.Expr("now()")).
+		Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar)
+	_, err := r.QueryManager.ExecSq(ctx, query)
+	return err
+}
+
+// MarkOutboxFailed postpones next attempt to publish outbox event.
+func (r *Repository) MarkOutboxFailed(ctx context.Context, id int64, nextAttemptAt time.Time) error {
+	query := sq.Update("outbox").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("next_attempt_at", nextAttemptAt).
+		Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar)
+	_, err := r.QueryManager.ExecSq(ctx, query)
+	return err
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"wb-tech-backend/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// dimensionColumns maps report dimensions to columns of reportQuery tables.
+var dimensionColumns = map[string]string{
+	"currency":         "p.currency",
+	"provider":         "p.provider",
+	"bank":             "p.bank",
+	"region":           "d.region",
+	"delivery_service": "o.delivery_service",
+}
+
+// reportQuery returns aggregation over orders joined with payments and deliveries filtered by q and
+// grouped by period, currency and dimensions of q. Its first columns are the grouping ones.
+func reportQuery(q models.ReportQuery) (sq.SelectBuilder, []string) {
+	query := sq.Select().From("orders o").
+		Join("payments p ON o.payment_id = p.payment_id").
+		Join("deliveries d ON o.delivery_id = d.delivery_id").PlaceholderFormat(sq.Dollar)
+	if q.Period != "" {
+		// period is validated by ReportQuery.Validate
+		query = query.Column("date_trunc('" + q.Period + "', o.date_created) AS period").GroupBy("period").OrderBy("period")
+	}
+	query = query.Column("p.currency").GroupBy("p.currency")
+	dimensions := make([]string, 0, len(q.GroupBy))
+	for _, d := range q.GroupBy {
+		if d == "currency" {
+			continue
+		}
+		query = query.Column(dimensionColumns[d]).GroupBy(dimensionColumns[d])
+		dimensions = append(dimensions, d)
+	}
+	if !q.From.IsZero() {
+		query = query.Where(sq.GtOrEq{"o.date_created": q.From})
+	}
+	if !q.To.IsZero() {
+		query = query.Where(sq.Lt{"o.date_created": q.To.AddDate(0, 0, 1)})
+	}
+	return query, dimensions
+}
+
+// scanGroup returns destinations of grouping columns of reportQuery.
+func scanGroup(q models.ReportQuery, group *models.ReportGroup, dimensions []string) ([]interface{}, func()) {
+	dest := make([]interface{}, 0, 2+len(dimensions))
+	var period time.Time
+	if q.Period != "" {
+		dest = append(dest, &period)
+	}
+	dest = append(dest, &group.Currency)
+	values := make([]*string, len(dimensions))
+	for i := range dimensions {
+		values[i] = new(string)
+		dest = append(dest, values[i])
+	}
+	return dest, func() {
+		if q.Period != "" {
+			group.Period = &period
+		}
+		if len(dimensions) > 0 {
+			group.Dimensions = make(map[string]string, len(dimensions))
+			for i, d := range dimensions {
+				group.Dimensions[d] = *values[i]
+			}
+		}
+	}
+}
+
+// RevenueReport returns payments totals of orders.
+func (r *Repository) RevenueReport(ctx context.Context, q models.ReportQuery) ([]models.RevenueRow, error) {
+	query, dimensions := reportQuery(q)
+	query = query.Columns("count(*)", "coalesce(sum(p.amount), 0)", "coalesce(sum(p.goods_total), 0)", "coalesce(sum(p.delivery_cost), 0)")
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report := make([]models.RevenueRow, 0)
+	for rows.Next() {
+		var row models.RevenueRow
+		dest, fill := scanGroup(q, &row.ReportGroup, dimensions)
+		if err = rows.Scan(append(dest, &row.Orders, &row.Amount, &row.GoodsTotal, &row.DeliveryCost)...); err != nil {
+			return nil, err
+		}
+		fill()
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
+
+// TopItemsReport returns up to limit the most sold items or, if byBrand is set, brands in every group.
+func (r *Repository) TopItemsReport(ctx context.Context, q models.ReportQuery, byBrand bool, limit int) ([]models.TopItemRow, error) {
+	query, dimensions := reportQuery(q)
+	query = query.Join("items i ON i.item_id = ANY(o.items_ids)").Column("i.brand").GroupBy("i.brand")
+	if !byBrand {
+		query = query.Columns("i.nm_id", "max(i.name)").GroupBy("i.nm_id")
+	}
+	query = query.Columns("count(*) AS quantity", "coalesce(sum(i.total_price), 0) AS revenue")
+
+	// rank items inside every group and keep the first limit of them, output aliases can't be used in window
+	partition := "p.currency"
+	if q.Period != "" {
+		partition = "date_trunc('" + q.Period + "', o.date_created), " + partition
+	}
+	for _, d := range dimensions {
+		partition += ", " + dimensionColumns[d]
+	}
+	query = query.Column("row_number() OVER (PARTITION BY " + partition + " ORDER BY sum(i.total_price) DESC) AS place")
+	// placeholders of subquery are numbered by the outer query
+	ranked := sq.Select("*").FromSelect(query.PlaceholderFormat(sq.Question), "ranked").Where(sq.LtOrEq{"place": limit}).PlaceholderFormat(sq.Dollar)
+	if q.Period != "" {
+		ranked = ranked.OrderBy("period")
+	}
+	ranked = ranked.OrderBy("place")
+
+	rows, err := r.QueryManager.QuerySq(ctx, ranked)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report := make([]models.TopItemRow, 0)
+	for rows.Next() {
+		var row models.TopItemRow
+		var place int64
+		dest, fill := scanGroup(q, &row.ReportGroup, dimensions)
+		dest = append(dest, &row.Brand)
+		if !byBrand {
+			dest = append(dest, &row.NmId, &row.Name)
+		}
+		if err = rows.Scan(append(dest, &row.Quantity, &row.Revenue, &place)...); err != nil {
+			return nil, err
+		}
+		fill()
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
+
+// DeliveryServicesReport returns number of orders and delivery costs by delivery service.
+func (r *Repository) DeliveryServicesReport(ctx context.Context, q models.ReportQuery) ([]models.DeliveryServiceRow, error) {
+	withoutService := q
+	withoutService.GroupBy = make([]string, 0, len(q.GroupBy))
+	for _, d := range q.GroupBy {
+		if d != "delivery_service" {
+			withoutService.GroupBy = append(withoutService.GroupBy, d)
+		}
+	}
+	query, dimensions := reportQuery(withoutService)
+	query = query.Column("o.delivery_service").GroupBy("o.delivery_service").
+		Columns("count(*)", "coalesce(sum(p.delivery_cost), 0)", "coalesce(avg(p.delivery_cost), 0)::float8")
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report := make([]models.DeliveryServiceRow, 0)
+	for rows.Next() {
+		var row models.DeliveryServiceRow
+		dest, fill := scanGroup(withoutService, &row.ReportGroup, dimensions)
+		dest = append(dest, &row.DeliveryService, &row.Orders, &row.DeliveryCost, &row.AverageDeliveryCost)
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		fill()
+		report = append(report, row)
+	}
+	return report, rows.Err()
+}
@@ -1,21 +1,30 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 
+	"wb-tech-backend/internal/cache"
 	"wb-tech-backend/internal/core"
 	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/pkg/keyring"
 	"wb-tech-backend/internal/pkg/pgdb"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+var ErrOrderExists = errors.New("order already exists with the same content")
+
 type Deps struct {
 	QueryManager       *pgdb.QueryManager
 	TransactionManager *pgdb.TransactionManager
-	Cash               map[string]models.Order
+	Cash               *cache.Tiered
+	Keyring            *keyring.Keyring
 }
 
 type Repository struct {
@@ -27,29 +36,55 @@ func NewRepository(ctx context.Context, cfg *core.Config) (*Repository, error) {
 	if err != nil {
 		return nil, err
 	}
-	qm := pgdb.NewQueryManager(pool)
-	tm := pgdb.NewTransactionManager(pool)
+	isoLevel, err := pgdb.ParseIsoLevel(cfg.Storage.IsolationLevel)
+	if err != nil {
+		return nil, err
+	}
+	timeouts := pgdb.Timeouts{Read: cfg.Storage.ReadTimeout, Write: cfg.Storage.WriteTimeout}
+	qm := pgdb.NewQueryManager(pool, timeouts)
+	tm := pgdb.NewTransactionManager(pool, timeouts, pgdb.TxConfig{
+		IsoLevel:      isoLevel,
+		Retries:       cfg.Storage.TxRetries,
+		RetryDelay:    cfg.Storage.TxRetryDelay,
+		MaxRetryDelay: cfg.Storage.TxMaxRetryDelay,
+	})
 	r := &Repository{
 		Deps{
 			QueryManager:       qm,
 			TransactionManager: tm,
-			Cash:               make(map[string]models.Order),
 		},
 	}
-	orders, err := r.GetOrders(ctx)
-	if err != nil {
-		return nil, err
+	if cfg.Storage.KeyringFile != "" {
+		if r.Keyring, err = keyring.Load(cfg.Storage.KeyringFile); err != nil {
+			return nil, fmt.Errorf("load keyring: %w", err)
+		}
 	}
-	for _, order := range orders {
-		r.Cash[order.OrderId] = order
+	r.Cash = cache.NewTiered(cache.NewMemory(), nil)
+	if cfg.Cache.Redis.Enabled {
+		var sealer cache.Sealer
+		if r.Keyring != nil {
+			sealer = r.Keyring
+		}
+		r.Cash.L2 = cache.NewRedis(cfg.Cache.Redis, sealer)
 	}
 	return r, nil
 }
 
+// Close releases connections of shared cache.
+func (r *Repository) Close() error {
+	if l2, ok := r.Cash.L2.(io.Closer); ok {
+		return l2.Close()
+	}
+	return nil
+}
+
 func (r *Repository) addDelivery(ctx context.Context, delivery models.Delivery) (int64, error) {
-	query := sq.Insert("deliveries").
-		Columns("name", "phone", "zip", "city", "address", "region", "email").
-		Values(delivery.Name, delivery.Phone, delivery.Zip, delivery.City, delivery.Address, delivery.Region, delivery.Email).
+	columns, err := r.sealDelivery(delivery)
+	if err != nil {
+		return 0, err
+	}
+	columns["zip"], columns["city"], columns["region"] = delivery.Zip, delivery.City, delivery.Region
+	query := sq.Insert("deliveries").SetMap(columns).
 		PlaceholderFormat(sq.Dollar).Suffix("RETURNING delivery_id")
 	rows, err := r.QueryManager.QuerySq(ctx, query)
 	if err != nil {
@@ -113,265 +148,381 @@ func (r *Repository) addItem(ctx context.Context, item models.Item) (int64, erro
 	return itemId, nil
 }
 
-func (r *Repository) AddOrder(ctx context.Context, order models.Order) error {
-	return r.TransactionManager.Tx(ctx, func(ctx context.Context) error {
-		deliveryId, err := r.addDelivery(ctx, order.Delivery)
-		if err != nil {
-			return err
-		}
-		paymentId, err := r.addPayment(ctx, order.Payment)
-		if err != nil {
-			return err
-		}
-		itemsIds := make([]int64, 0)
-		for _, item := range order.Items {
-			itemId, err := r.addItem(ctx, item)
-			if err != nil {
-				return err
-			}
-			itemsIds = append(itemsIds, itemId)
-		}
-		query := sq.Insert("orders").
-			Columns("order_uid", "track_number", "entry", "delivery_id", "payment_id", "items_ids", "locale", "internal_signature", "customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard").
-			Values(order.OrderId, order.TrackNumber, order.Entry, deliveryId, paymentId, itemsIds, order.Locale, order.InternalSignature, order.CustomerId, order.DeliveryService, order.Shardkey, order.SmId, order.DateCreated, order.OofShard).
-			PlaceholderFormat(sq.Dollar).Suffix("RETURNING order_uid")
-		rows, err := r.QueryManager.QuerySq(ctx, query)
+type orderRefs struct {
+	deliveryId int64
+	paymentId  int64
+	itemsIds   []int64
+}
+
+// lockOrder locks stored order row and returns ids of its delivery, payment and items.
+func (r *Repository) lockOrder(ctx context.Context, orderId string) (orderRefs, bool, error) {
+	query := sq.Select("delivery_id", "payment_id", "items_ids").From("orders").
+		Where(sq.Eq{"order_uid": orderId}).Suffix("FOR UPDATE").PlaceholderFormat(sq.Dollar)
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
+		return orderRefs{}, false, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return orderRefs{}, false, rows.Err()
+	}
+	var refs orderRefs
+	if err = rows.Scan(&refs.deliveryId, &refs.paymentId, &refs.itemsIds); err != nil {
+		return orderRefs{}, false, err
+	}
+	return refs, true, nil
+}
+
+func (r *Repository) addItems(ctx context.Context, items []models.Item) ([]int64, error) {
+	itemsIds := make([]int64, 0, len(items))
+	for _, item := range items {
+		itemId, err := r.addItem(ctx, item)
 		if err != nil {
-			return err
-		}
-		if err = rows.Err(); err != nil {
-			return err
-		}
-		defer rows.Close()
-		var orderId string
-		for rows.Next() {
-			err = rows.Scan(&orderId)
-			if err != nil {
-				return err
-			}
-		}
-		if orderId == order.OrderId {
-			r.Cash[order.OrderId] = order
-			return nil
+			return nil, err
 		}
-		return fmt.Errorf("something goes wrong with add order to database")
-	})
+		itemsIds = append(itemsIds, itemId)
+	}
+	return itemsIds, nil
 }
 
-func (r *Repository) GetOrderById(ctx context.Context, orderId string) (models.Order, error) {
-	query := sq.Select("o.order_uid", "o.track_number", "o.entry", "o.items_ids", "o.locale", "o.internal_signature", "o.customer_id", "o.delivery_service", "o.shardkey", "o.sm_id", "o.date_created", "o.oof_shard",
-		"d.name", "d.phone", "d.zip", "d.city", "d.address", "d.region", "d.email",
-		"p.transaction", "p.request_id", "p.currency", "p.provider", "p.amount", "p.payment_dt", "p.bank", "p.delivery_cost", "p.goods_total", "p.custom_fee").
-		From("orders o").Join("deliveries d ON o.delivery_id = d.delivery_id").
-		Join("payments p ON o.payment_id = p.payment_id").Where(sq.Eq{"o.order_uid": orderId}).PlaceholderFormat(sq.Dollar)
+func (r *Repository) insertOrder(ctx context.Context, order models.Order) (int64, error) {
+	deliveryId, err := r.addDelivery(ctx, order.Delivery)
+	if err != nil {
+		return 0, err
+	}
+	paymentId, err := r.addPayment(ctx, order.Payment)
+	if err != nil {
+		return 0, err
+	}
+	itemsIds, err := r.addItems(ctx, order.Items)
+	if err != nil {
+		return 0, err
+	}
+	query := sq.Insert("orders").
+		Columns("order_uid", "track_number", "entry", "delivery_id", "payment_id", "items_ids", "locale", "internal_signature", "customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard").
+		Values(order.OrderId, order.TrackNumber, order.Entry, deliveryId, paymentId, itemsIds, order.Locale, order.InternalSignature, order.CustomerId, order.DeliveryService, order.Shardkey, order.SmId, order.DateCreated, order.OofShard).
+		PlaceholderFormat(sq.Dollar).Suffix("RETURNING order_uid, seq")
 	rows, err := r.QueryManager.QuerySq(ctx, query)
 	if err != nil {
-		return models.Order{}, err
+		return 0, err
 	}
 	if err = rows.Err(); err != nil {
-		return models.Order{}, err
+		return 0, err
 	}
-	var order models.Order
-	var itemsIds []int64
+	defer rows.Close()
+	var orderId string
+	var seq int64
 	for rows.Next() {
-		err = rows.Scan(
-			&order.OrderId, &order.TrackNumber, &order.Entry, &itemsIds, &order.Locale, &order.InternalSignature,
-			&order.CustomerId, &order.DeliveryService, &order.Shardkey, &order.SmId, &order.DateCreated,
-			&order.OofShard, &order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip,
-			&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
-			&order.Payment.Transaction, &order.Payment.RequestId, &order.Payment.Currency, &order.Payment.Provider,
-			&order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank, &order.Payment.DeliveryCost,
-			&order.Payment.GoodsTotal, &order.Payment.CustomFee,
-		)
+		err = rows.Scan(&orderId, &seq)
 		if err != nil {
-			return models.Order{}, err
+			return 0, err
+		}
+	}
+	if orderId != order.OrderId {
+		return 0, fmt.Errorf("something goes wrong with add order to database")
+	}
+	return seq, nil
+}
+
+// updateOrder overwrites stored order, its delivery and payment in place and replaces its items.
+// Updated order gets new ingestion sequence number.
+func (r *Repository) updateOrder(ctx context.Context, order models.Order, refs orderRefs) (int64, error) {
+	d := order.Delivery
+	columns, err := r.sealDelivery(d)
+	if err != nil {
+		return 0, err
+	}
+	columns["zip"], columns["city"], columns["region"] = d.Zip, d.City, d.Region
+	_, err = r.QueryManager.ExecSq(ctx, sq.Update("deliveries").SetMap(columns).
+		Where(sq.Eq{"delivery_id": refs.deliveryId}).PlaceholderFormat(sq.Dollar))
+	if err != nil {
+		return 0, err
+	}
+	p := order.Payment
+	_, err = r.QueryManager.ExecSq(ctx, sq.Update("payments").SetMap(map[string]interface{}{
+		"transaction": p.Transaction, "request_id": p.RequestId, "currency": p.Currency, "provider": p.Provider, "amount": p.Amount,
+		"payment_dt": p.PaymentDt, "bank": p.Bank, "delivery_cost": p.DeliveryCost, "goods_total": p.GoodsTotal, "custom_fee": p.CustomFee,
+	}).Where(sq.Eq{"payment_id": refs.paymentId}).PlaceholderFormat(sq.Dollar))
+	if err != nil {
+		return 0, err
+	}
+	itemsIds, err := r.addItems(ctx, order.Items)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := r.QueryManager.QuerySq(ctx, sq.Update("orders").SetMap(map[string]interface{}{
+		"track_number": order.TrackNumber, "entry": order.Entry, "items_ids": itemsIds, "locale": order.Locale,
+		"internal_signature": order.InternalSignature, "customer_id": order.CustomerId, "delivery_service": order.DeliveryService,
+		"shardkey": order.Shardkey, "sm_id": order.SmId, "date_created": order.DateCreated, "oof_shard": order.OofShard,
+		"seq": sq.Expr("nextval('orders_seq')"),
+	}).Where(sq.Eq{"order_uid": order.OrderId}).Suffix("RETURNING seq").PlaceholderFormat(sq.Dollar))
+	if err != nil {
+		return 0, err
+	}
+	var seq int64
+	for rows.Next() {
+		if err = rows.Scan(&seq); err != nil {
+			rows.Close()
+			return 0, err
 		}
 	}
 	rows.Close()
-	defer rows.Close()
-	for _, itemId := range itemsIds {
-		query = sq.Select("i.chrt_id", "i.track_number", "i.price", "i.rid", "i.name", "i.sale", "i.size", "i.total_price", "i.nm_id", "i.brand", "i.status").
-			From("items i").Where(sq.Eq{"item_id": itemId}).PlaceholderFormat(sq.Dollar)
-		rows, err = r.QueryManager.QuerySq(ctx, query)
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(refs.itemsIds) > 0 {
+		_, err = r.QueryManager.ExecSq(ctx, sq.Delete("items").Where(sq.Eq{"item_id": refs.itemsIds}).PlaceholderFormat(sq.Dollar))
 		if err != nil {
-			return models.Order{}, err
-		}
-		if err = rows.Err(); err != nil {
-			return models.Order{}, err
+			return 0, err
 		}
+	}
+	return seq, nil
+}
 
-		for rows.Next() {
-			var item models.Item
-			err = rows.Scan(
-				&item.ChrtId, &item.TrackNumber, &item.Price, &item.RId, &item.Name, &item.Sale,
-				&item.Size, &item.TotalPrice, &item.NmId, &item.Brand, &item.Status,
-			)
+// AddOrder stores new order or updates already stored one and writes corresponding event to outbox
+// within the same transaction. Stored order is returned with its ingestion sequence number.
+// ErrOrderExists is returned if the order is already stored unchanged.
+func (r *Repository) AddOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	var stored models.Order
+	err := r.TransactionManager.Tx(ctx, func(ctx context.Context) (err error) {
+		stored, err = r.saveOrder(ctx, order)
+		return err
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+	r.Cash.Set(stored)
+	return stored, nil
+}
+
+// ImportOrders stores historical orders like AddOrder but within a single transaction, so either all of
+// them are stored or none. Outbox events are not written and new orders are not cached, so bulk import
+// doesn't flood subscribers and evict recent orders, but updated orders replace stale cached ones.
+// Orders already stored unchanged are skipped. Number of stored orders and updated ones are returned.
+func (r *Repository) ImportOrders(ctx context.Context, orders []models.Order) (int, []models.Order, error) {
+	var (
+		n       int
+		changed []models.Order
+	)
+	err := r.TransactionManager.Tx(ctx, func(ctx context.Context) error {
+		// transaction may be retried
+		n, changed = 0, changed[:0]
+		for _, order := range orders {
+			stored, updated, err := r.storeOrder(ctx, order)
+			if errors.Is(err, ErrOrderExists) {
+				continue
+			}
 			if err != nil {
-				return models.Order{}, err
+				return fmt.Errorf("order %s: %w", order.OrderId, err)
+			}
+			n++
+			if updated {
+				changed = append(changed, stored)
 			}
-			order.Items = append(order.Items, item)
 		}
-		rows.Close()
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
 	}
+	for _, order := range changed {
+		r.Cash.Set(order)
+	}
+	return n, changed, nil
+}
 
-	return order, nil
+// saveOrder stores order and writes corresponding event to outbox within transaction of ctx.
+func (r *Repository) saveOrder(ctx context.Context, order models.Order) (models.Order, error) {
+	order, updated, err := r.storeOrder(ctx, order)
+	if err != nil {
+		return order, err
+	}
+	eventType := models.EventOrderCreated
+	if updated {
+		eventType = models.EventOrderUpdated
+	}
+	return order, r.addOutboxEvent(ctx, eventType, order)
 }
-func (r *Repository) GetOrders(ctx context.Context) ([]models.Order, error) {
-	query := sq.Select("o.order_uid", "o.track_number", "o.entry", "o.items_ids", "o.locale", "o.internal_signature", "o.customer_id", "o.delivery_service", "o.shardkey", "o.sm_id", "o.date_created", "o.oof_shard",
-		"d.name", "d.phone", "d.zip", "d.city", "d.address", "d.region", "d.email",
-		"p.transaction", "p.request_id", "p.currency", "p.provider", "p.amount", "p.payment_dt", "p.bank", "p.delivery_cost", "p.goods_total", "p.custom_fee").
+
+// storeOrder inserts new order or updates already stored one within transaction of ctx and reports
+// whether the order was updated.
+func (r *Repository) storeOrder(ctx context.Context, order models.Order) (models.Order, bool, error) {
+	refs, exists, err := r.lockOrder(ctx, order.OrderId)
+	if err != nil {
+		return order, false, err
+	}
+	if !exists {
+		order.Seq, err = r.insertOrder(ctx, order)
+		return order, false, err
+	}
+	stored, err := r.GetOrderById(ctx, order.OrderId)
+	if err != nil {
+		return order, false, err
+	}
+	equal, err := sameOrders(stored, order)
+	if err != nil {
+		return order, false, err
+	}
+	if equal {
+		return order, false, ErrOrderExists
+	}
+	order.Seq, err = r.updateOrder(ctx, order, refs)
+	return order, true, err
+}
+
+func sameOrders(a, b models.Order) (bool, error) {
+	aJson, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	bJson, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(aJson, bJson), nil
+}
+
+// ordersQuery returns query selecting orders joined with their deliveries and payments.
+func ordersQuery() sq.SelectBuilder {
+	columns := []string{"o.order_uid", "o.track_number", "o.entry", "o.items_ids", "o.locale", "o.internal_signature", "o.customer_id", "o.delivery_service", "o.shardkey", "o.sm_id", "o.date_created", "o.oof_shard", "o.seq",
+		"d.zip", "d.city", "d.region",
+		"p.transaction", "p.request_id", "p.currency", "p.provider", "p.amount", "p.payment_dt", "p.bank", "p.delivery_cost", "p.goods_total", "p.custom_fee"}
+	return sq.Select(append(columns, deliveryPIIColumns...)...).
 		From("orders o").Join("deliveries d ON o.delivery_id = d.delivery_id").
 		Join("payments p ON o.payment_id = p.payment_id").PlaceholderFormat(sq.Dollar)
-	rows, err := r.QueryManager.QuerySq(ctx, query)
+}
+
+// OrderRecord is stored order without items and ids of its items.
+type OrderRecord struct {
+	models.Order
+	ItemsIds []int64
+}
+
+// queryOrders executes query built by ordersQuery and loads items of selected orders.
+func (r *Repository) queryOrders(ctx context.Context, query sq.Sqlizer) ([]models.Order, error) {
+	records, err := r.queryOrderRecords(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	if err = rows.Err(); err != nil {
+	return r.withItems(ctx, records)
+}
+
+// withItems loads items of orders.
+func (r *Repository) withItems(ctx context.Context, records []OrderRecord) ([]models.Order, error) {
+	allIds := make([]int64, 0)
+	for _, record := range records {
+		allIds = append(allIds, record.ItemsIds...)
+	}
+	items, err := r.GetItems(ctx, allIds)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]models.Order, 0, len(records))
+	for _, record := range records {
+		for _, id := range record.ItemsIds {
+			if item, ok := items[id]; ok {
+				record.Items = append(record.Items, item)
+			}
+		}
+		orders = append(orders, record.Order)
+	}
+	return orders, nil
+}
+
+// queryOrderRecords executes query built by ordersQuery, or fetching from cursor declared for it,
+// without loading items.
+func (r *Repository) queryOrderRecords(ctx context.Context, query sq.Sqlizer) ([]OrderRecord, error) {
+	rows, err := r.QueryManager.QuerySq(ctx, query)
+	if err != nil {
 		return nil, err
 	}
-	orders := make([]models.Order, 0)
-	var itemsIds []int64
+	defer rows.Close()
+	records := make([]OrderRecord, 0)
 	for rows.Next() {
-		var order models.Order
-		err = rows.Scan(
-			&order.OrderId, &order.TrackNumber, &order.Entry, &itemsIds, &order.Locale, &order.InternalSignature,
+		var record OrderRecord
+		var sealed sealedDelivery
+		order := &record.Order
+		dest := []interface{}{
+			&order.OrderId, &order.TrackNumber, &order.Entry, &record.ItemsIds, &order.Locale, &order.InternalSignature,
 			&order.CustomerId, &order.DeliveryService, &order.Shardkey, &order.SmId, &order.DateCreated,
-			&order.OofShard, &order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip,
-			&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
+			&order.OofShard, &order.Seq, &order.Delivery.Zip, &order.Delivery.City, &order.Delivery.Region,
 			&order.Payment.Transaction, &order.Payment.RequestId, &order.Payment.Currency, &order.Payment.Provider,
 			&order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank, &order.Payment.DeliveryCost,
 			&order.Payment.GoodsTotal, &order.Payment.CustomFee,
-		)
-		if err != nil {
+		}
+		if err = rows.Scan(append(dest, sealed.scanDest()...)...); err != nil {
 			return nil, err
 		}
-		orders = append(orders, order)
-	}
-	rows.Close()
-	for _, order := range orders {
-		for _, itemId := range itemsIds {
-			query = sq.Select("i.chrt_id", "i.track_number", "i.price", "i.rid", "i.name", "i.sale", "i.size", "i.total_price", "i.nm_id", "i.brand", "i.status").
-				From("items i").Where(sq.Eq{"item_id": itemId}).PlaceholderFormat(sq.Dollar)
-			rows, err = r.QueryManager.QuerySq(ctx, query)
-			if err != nil {
-				return nil, err
-			}
-			if err = rows.Err(); err != nil {
-				return nil, err
-			}
-
-			for rows.Next() {
-				var item models.Item
-				err = rows.Scan(
-					&item.ChrtId, &item.TrackNumber, &item.Price, &item.RId, &item.Name, &item.Sale,
-					&item.Size, &item.TotalPrice, &item.NmId, &item.Brand, &item.Status,
-				)
-				if err != nil {
-					return nil, err
-				}
-				order.Items = append(order.Items, item)
-			}
-			rows.Close()
+		if err = r.openDelivery(sealed, &order.Delivery); err != nil {
+			return nil, fmt.Errorf("order %s: %w", order.OrderId, err)
 		}
+		records = append(records, record)
 	}
-	return orders, nil
+	return records, rows.Err()
 }
-func (r *Repository) GetOrders2(ctx context.Context) ([]models.Order, error) {
-	query := sq.Select("*").From("orders").PlaceholderFormat(sq.Dollar)
+
+// GetItems returns items with given ids by their ids.
+func (r *Repository) GetItems(ctx context.Context, ids []int64) (map[int64]models.Item, error) {
+	items := make(map[int64]models.Item, len(ids))
+	if len(ids) == 0 {
+		return items, nil
+	}
+	query := sq.Select("i.item_id", "i.chrt_id", "i.track_number", "i.price", "i.rid", "i.name", "i.sale", "i.size", "i.total_price", "i.nm_id", "i.brand", "i.status").
+		From("items i").Where("i.item_id = ANY(?)", ids).PlaceholderFormat(sq.Dollar)
 	rows, err := r.QueryManager.QuerySq(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-	orders := make([]models.Order, 0)
-	var itemsIDs []int64
+	defer rows.Close()
 	for rows.Next() {
-		var order Order
+		var id int64
+		var item models.Item
 		err = rows.Scan(
-			&order.OrderId, &order.TrackNumber, &order.Entry, &order.DeliveryId,
-			&order.PaymentId, &itemsIDs, &order.Locale, &order.InternalSignature,
-			&order.CustomerId, &order.DeliveryService, &order.Shardkey, &order.SmId,
-			&order.DateCreated, &order.OofShard,
+			&id, &item.ChrtId, &item.TrackNumber, &item.Price, &item.RId, &item.Name, &item.Sale,
+			&item.Size, &item.TotalPrice, &item.NmId, &item.Brand, &item.Status,
 		)
 		if err != nil {
 			return nil, err
 		}
-		rows.Close()
+		items[id] = item
+	}
+	return items, rows.Err()
+}
 
-		query = sq.Select("*").From("deliveries").Where(sq.Eq{"delivery_id": order.DeliveryId}).PlaceholderFormat(sq.Dollar)
-		rows, err = r.QueryManager.QuerySq(ctx, query)
-		if err != nil {
-			return nil, err
-		}
-		if err = rows.Err(); err != nil {
-			return nil, err
-		}
-		var d models.Delivery
-		var id int64
-		for rows.Next() {
-			err = rows.Scan(&id, &d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email)
-			if err != nil {
-				return nil, err
-			}
-		}
-		rows.Close()
+func (r *Repository) GetOrderById(ctx context.Context, orderId string) (models.Order, error) {
+	orders, err := r.queryOrders(ctx, ordersQuery().Where(sq.Eq{"o.order_uid": orderId}))
+	if err != nil {
+		return models.Order{}, err
+	}
+	if len(orders) == 0 {
+		return models.Order{}, nil
+	}
+	return orders[0], nil
+}
 
-		query = sq.Select("*").From("payments").Where(sq.Eq{"payment_id": order.PaymentId}).PlaceholderFormat(sq.Dollar)
-		rows, err = r.QueryManager.QuerySq(ctx, query)
-		if err != nil {
-			return nil, err
-		}
-		if err = rows.Err(); err != nil {
-			return nil, err
-		}
-		var p models.Payment
-		for rows.Next() {
-			err = rows.Scan(&id, &p.Transaction, &p.RequestId, &p.Currency, &p.Provider, &p.Amount, &p.PaymentDt, &p.Bank, &p.DeliveryCost, &p.GoodsTotal, &p.CustomFee)
-			if err != nil {
-				return nil, err
-			}
-		}
-		rows.Close()
+// GetOrdersAfterSeq returns up to limit orders ingested after seq ordered by ingestion sequence.
+func (r *Repository) GetOrdersAfterSeq(ctx context.Context, seq int64, filter models.OrderFilter, limit uint64) ([]models.Order, error) {
+	query := filterOrders(ordersQuery(), filter).Where(sq.Gt{"o.seq": seq}).OrderBy("o.seq").Limit(limit)
+	return r.queryOrders(ctx, query)
+}
 
-		items := make([]models.Item, 0)
-		for _, itemId := range itemsIDs {
-			query = sq.Select("*").From("items").Where(sq.Eq{"item_id": itemId}).PlaceholderFormat(sq.Dollar)
-			rows, err = r.QueryManager.QuerySq(ctx, query)
-			if err != nil {
-				return nil, err
-			}
-			if err = rows.Err(); err != nil {
-				return nil, err
-			}
-			var i models.Item
-			for rows.Next() {
-				err = rows.Scan(&id, &i.ChrtId, &i.TrackNumber, &i.Price, &i.RId, &i.Name, &i.Sale, &i.Size, &i.TotalPrice, &i.NmId, &i.Brand, &i.Status)
-				if err != nil {
-					return nil, err
-				}
-			}
-			items = append(items, i)
-			rows.Close()
-		}
-		ord := models.Order{
-			OrderId:           order.OrderId,
-			TrackNumber:       order.TrackNumber,
-			Entry:             order.Entry,
-			Delivery:          d,
-			Payment:           p,
-			Items:             items,
-			Locale:            order.Locale,
-			InternalSignature: order.InternalSignature,
-			CustomerId:        order.CustomerId,
-			DeliveryService:   order.DeliveryService,
-			Shardkey:          order.Shardkey,
-			SmId:              order.SmId,
-			DateCreated:       order.DateCreated,
-			OofShard:          order.OofShard,
-		}
-		orders = append(orders, ord)
+// FindOrders returns up to limit orders matching filter newest first, skipping offset orders.
+// Items are not loaded, they can be loaded for many orders at once by GetItems.
+func (r *Repository) FindOrders(ctx context.Context, filter models.OrderFilter, limit, offset uint64) ([]OrderRecord, error) {
+	query := filterOrders(ordersQuery(), filter).OrderBy("o.seq DESC").Limit(limit).Offset(offset)
+	return r.queryOrderRecords(ctx, query)
+}
+
+func filterOrders(query sq.SelectBuilder, filter models.OrderFilter) sq.SelectBuilder {
+	if filter.CustomerId != "" {
+		query = query.Where(sq.Eq{"o.customer_id": filter.CustomerId})
 	}
-	return orders, nil
+	if filter.DeliveryService != "" {
+		query = query.Where(sq.Eq{"o.delivery_service": filter.DeliveryService})
+	}
+	return query
+}
+
+func (r *Repository) GetOrders(ctx context.Context) ([]models.Order, error) {
+	return r.queryOrders(ctx, ordersQuery())
 }
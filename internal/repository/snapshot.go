@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"wb-tech-backend/internal/cache"
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/models"
+)
+
+// snapshotField authenticates encrypted snapshot payload.
+const snapshotField = "cache_snapshot"
+
+// SaveSnapshot writes cached orders to snapshot file. With keyring configured snapshot is encrypted
+// with a new data key, since it contains personal data.
+func (r *Repository) SaveSnapshot(path string) error {
+	started := time.Now()
+	payload, meta, err := r.Cash.Encode()
+	if err != nil {
+		return err
+	}
+	if r.Keyring != nil {
+		dk, err := r.Keyring.NewDataKey()
+		if err != nil {
+			return err
+		}
+		if payload, err = dk.Encrypt(snapshotField, string(payload)); err != nil {
+			return err
+		}
+		meta.KeyId, meta.WrappedKey = dk.KeyId, dk.Wrapped
+	}
+	if err = cache.WriteSnapshot(path, meta, payload); err != nil {
+		return err
+	}
+	slog.Info("Cache snapshot saved", "orders", meta.Count, "seq", meta.Seq, "bytes", len(payload), "elapsed", time.Since(started))
+	return nil
+}
+
+// RunSnapshots saves snapshot every interval until ctx is done.
+func (r *Repository) RunSnapshots(ctx context.Context, cfg core.CacheConfig) error {
+	ticker := time.NewTicker(cfg.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.SaveSnapshot(cfg.SnapshotFile); err != nil {
+				slog.Error("Error with save cache snapshot", "error", err)
+			}
+		}
+	}
+}
+
+// restoreSnapshot loads orders from snapshot file into cache and then orders stored or updated after
+// snapshot high-water mark from storage.
+func (r *Repository) restoreSnapshot(ctx context.Context, cfg core.CacheConfig) error {
+	started := time.Now()
+	meta, payload, err := cache.ReadSnapshot(cfg.SnapshotFile)
+	if err != nil {
+		return err
+	}
+	if meta.KeyId != "" {
+		if r.Keyring == nil {
+			return errors.New("snapshot is encrypted, but keyring is not configured")
+		}
+		dk, err := r.Keyring.OpenDataKey(meta.KeyId, meta.WrappedKey)
+		if err != nil {
+			return err
+		}
+		plaintext, err := dk.Decrypt(snapshotField, payload)
+		if err != nil {
+			return err
+		}
+		payload = []byte(plaintext)
+	}
+	restored, err := r.Cash.Restore(payload)
+	if err != nil {
+		return err
+	}
+	slog.Info("Cache snapshot loaded", "orders", restored, "seq", meta.Seq, "created", meta.Created, "elapsed", time.Since(started))
+
+	batchSize := cfg.WarmUpBatch
+	if batchSize <= 0 {
+		batchSize = defaultWarmUpBatch
+	}
+	seq, reconciled := meta.Seq, 0
+	for {
+		orders, err := r.GetOrdersAfterSeq(ctx, seq, models.OrderFilter{}, uint64(batchSize))
+		if err != nil {
+			return fmt.Errorf("reconcile cache snapshot: %w", err)
+		}
+		if len(orders) == 0 {
+			break
+		}
+		for _, order := range orders {
+			r.Cash.Memory.SetIfNewer(order)
+		}
+		reconciled += len(orders)
+		warmUpOrders.Add(float64(len(orders)))
+		seq = orders[len(orders)-1].Seq
+	}
+	warmUpDuration.Set(time.Since(started).Seconds())
+	slog.Info("Cache reconciled with storage", "orders", reconciled, "cached", r.Cash.Len(), "elapsed", time.Since(started))
+	return nil
+}
+
+// snapshotRestoreFailed logs error of snapshot restoring, which is followed by full warm-up.
+func snapshotRestoreFailed(err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("Cache snapshot not found, warming up from storage")
+		return
+	}
+	slog.Warn("Error with load cache snapshot, warming up from storage", "error", err)
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"wb-tech-backend/internal/core"
+	"wb-tech-backend/internal/models"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	warmUpCursor       = "warm_up_orders"
+	defaultWarmUpBatch = 1000
+	warmUpLogInterval  = 10 * time.Second
+)
+
+var (
+	warmUpOrders = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cache_warmup_orders_total",
+		Help: "Number of orders loaded into cache by warm-up.",
+	})
+	warmUpDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cache_warmup_duration_seconds",
+		Help: "Duration of the last finished cache warm-up.",
+	})
+)
+
+// WarmUpCache loads cache from snapshot file if it is configured and valid, otherwise loads stored orders
+// newest first, page by page from server-side cursor, so memory is not spent on more than one page besides
+// the cache. Orders cached while warm-up is running are not overwritten by older versions.
+func (r *Repository) WarmUpCache(ctx context.Context, cfg core.CacheConfig) error {
+	if cfg.SnapshotFile != "" {
+		err := r.restoreSnapshot(ctx, cfg)
+		if err == nil {
+			return nil
+		}
+		snapshotRestoreFailed(err)
+	}
+
+	batchSize := cfg.WarmUpBatch
+	if batchSize <= 0 {
+		batchSize = defaultWarmUpBatch
+	}
+	query := ordersQuery().OrderBy("o.seq DESC")
+	if cfg.WarmUpLimit > 0 {
+		query = query.Limit(cfg.WarmUpLimit)
+	}
+	if cfg.WarmUpMaxAge > 0 {
+		query = query.Where(sq.GtOrEq{"o.date_created": time.Now().UTC().Add(-cfg.WarmUpMaxAge)})
+	}
+
+	started := time.Now()
+	lastLog := started
+	var loaded int
+	err := r.fetchOrders(ctx, warmUpCursor, query, batchSize, func(orders []models.Order) error {
+		// warm-up fills local cache only, shared cache is filled on demand
+		for _, order := range orders {
+			r.Cash.Memory.SetIfNewer(order)
+		}
+		loaded += len(orders)
+		warmUpOrders.Add(float64(len(orders)))
+		if time.Since(lastLog) >= warmUpLogInterval {
+			lastLog = time.Now()
+			slog.Info("Cache warm-up in progress", "loaded", loaded, "elapsed", time.Since(started))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	warmUpDuration.Set(time.Since(started).Seconds())
+	slog.Info("Cache warm-up finished", "loaded", loaded, "cached", r.Cash.Len(), "elapsed", time.Since(started))
+	return nil
+}
@@ -2,22 +2,64 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 
+	"wb-tech-backend/internal/cache"
 	"wb-tech-backend/internal/core"
 	"wb-tech-backend/internal/models"
+	"wb-tech-backend/internal/pkg/pgdb"
 	"wb-tech-backend/internal/repository"
+	"wb-tech-backend/internal/stream"
+)
+
+var (
+	ErrOrderNotFound    = errors.New("order not found")
+	ErrCustomerNotFound = errors.New("customer not found")
+	// ErrStorageTimeout matches errors of storage operations cancelled by timeout.
+	ErrStorageTimeout = pgdb.ErrTimeout
 )
 
 type Repository interface {
-	AddOrder(ctx context.Context, order models.Order) error
+	AddOrder(ctx context.Context, order models.Order) (models.Order, error)
+	ImportOrders(ctx context.Context, orders []models.Order) (int, []models.Order, error)
 	GetOrderById(ctx context.Context, orderId string) (models.Order, error)
-	GetOrders(ctx context.Context) ([]models.Order, error)
+	GetOrdersAfterSeq(ctx context.Context, seq int64, filter models.OrderFilter, limit uint64) ([]models.Order, error)
+	FindOrders(ctx context.Context, filter models.OrderFilter, limit, offset uint64) ([]repository.OrderRecord, error)
+	GetItems(ctx context.Context, ids []int64) (map[int64]models.Item, error)
+	GetCustomerOrders(ctx context.Context, customerId string, limit, offset uint64) ([]models.Order, error)
+	GetCustomerSummary(ctx context.Context, customerId string) (models.CustomerSummary, error)
+	RevenueReport(ctx context.Context, q models.ReportQuery) ([]models.RevenueRow, error)
+	TopItemsReport(ctx context.Context, q models.ReportQuery, byBrand bool, limit int) ([]models.TopItemRow, error)
+	DeliveryServicesReport(ctx context.Context, q models.ReportQuery) ([]models.DeliveryServiceRow, error)
+	ExportOrders(ctx context.Context, filter models.OrderFilter, batchSize int, handle func([]models.Order) error) error
+	GetOrdersByContact(ctx context.Context, email, phone string) ([]models.Order, error)
+	WarmUpCache(ctx context.Context, cfg core.CacheConfig) error
+	RunSnapshots(ctx context.Context, cfg core.CacheConfig) error
+}
+
+// CacheNotifier notifies other service replicas that order was changed, so they update their caches.
+type CacheNotifier interface {
+	NotifyOrderChanged(order models.Order) error
 }
 
+// exportBatchSize is the number of orders read from storage at once by ExportOrders.
+const exportBatchSize = 500
+
+// missesSize is the maximum number of remembered ids of missing orders.
+const missesSize = 100_000
+
 type Deps struct {
-	Repository *repository.Repository
-	Config     *core.Config
+	Repository Repository
+	// Cache is the orders cache filled by Repository.
+	Cache *cache.Tiered
+	// Misses is nil if missing orders are looked up in storage every time.
+	Misses *cache.Misses
+	Config *core.Config
+	Hub    *stream.Hub
+	// CacheNotifier is nil if service runs as a single replica.
+	CacheNotifier CacheNotifier
 }
 
 type Service struct {
@@ -25,28 +67,205 @@ type Service struct {
 }
 
 func NewService(r *repository.Repository, cfg *core.Config) *Service {
-	return &Service{
+	s := &Service{
 		Deps{
 			Repository: r,
+			Cache:      r.Cash,
 			Config:     cfg,
+			Hub:        stream.NewHub(),
 		}}
+	if cfg.Cache.MissTTL > 0 {
+		s.Misses = cache.NewMisses(cfg.Cache.MissTTL, missesSize)
+	}
+	return s
 }
 
+// AddOrder saves order and broadcasts it to order stream subscribers. Order that is already stored
+// is skipped, so redelivered messages are not treated as errors.
 func (s Service) AddOrder(ctx context.Context, order models.Order) error {
-	return s.Repository.AddOrder(ctx, order)
+	stored, err := s.Repository.AddOrder(ctx, order)
+	if errors.Is(err, repository.ErrOrderExists) {
+		slog.Info("Skip already stored order", "order_uid", order.OrderId)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.Misses.Delete(stored.OrderId)
+	s.Hub.Publish(stored)
+	if s.CacheNotifier != nil {
+		if err = s.CacheNotifier.NotifyOrderChanged(stored); err != nil {
+			slog.Error("Error with notify replicas", "order_uid", stored.OrderId, "error", err)
+		}
+	}
+	return nil
+}
+
+// ImportOrders stores historical orders in one transaction and returns number of stored ones. Other
+// replicas are notified of updated orders, so they don't serve stale ones from their caches.
+func (s Service) ImportOrders(ctx context.Context, orders []models.Order) (int, error) {
+	n, updated, err := s.Repository.ImportOrders(ctx, orders)
+	if err != nil {
+		return 0, err
+	}
+	for _, order := range updated {
+		s.Misses.Delete(order.OrderId)
+		if s.CacheNotifier == nil {
+			continue
+		}
+		if err = s.CacheNotifier.NotifyOrderChanged(order); err != nil {
+			slog.Error("Error with notify replicas", "order_uid", order.OrderId, "error", err)
+		}
+	}
+	return n, nil
+}
+
+// RefreshOrder replaces cached order with stored one, e.g. after it was changed directly in storage.
+// Order is removed from cache if it is not stored anymore.
+func (s Service) RefreshOrder(ctx context.Context, orderId string) error {
+	if _, ok := s.Cache.Memory.Get(orderId); !ok && s.Cache.L2 == nil {
+		s.Misses.Delete(orderId)
+		return nil
+	}
+	order, err := s.Repository.GetOrderById(ctx, orderId)
+	if err != nil {
+		return err
+	}
+	if order.OrderId == "" {
+		s.Cache.Delete(orderId)
+		return nil
+	}
+	s.Misses.Delete(orderId)
+	s.Cache.Set(order)
+	return nil
 }
+
+// ApplyOrderChange updates cached order changed by another replica. Order is read from storage unless
+// cached order is not older than seq and is removed from cache if it is not stored anymore.
+// New orders are broadcast to order stream subscribers of this replica.
+func (s Service) ApplyOrderChange(ctx context.Context, orderId string, seq int64) error {
+	// shared cache may already have the order, but it must be broadcast to local subscribers
+	cached, ok := s.Cache.Memory.Get(orderId)
+	if ok && seq > 0 && cached.Seq >= seq {
+		return nil
+	}
+	order, err := s.Repository.GetOrderById(ctx, orderId)
+	if err != nil {
+		return err
+	}
+	if order.OrderId == "" {
+		s.Cache.Delete(orderId)
+		return nil
+	}
+	s.Misses.Delete(orderId)
+	s.Cache.SetIfNewer(order)
+	if !ok || cached.Seq < order.Seq {
+		s.Hub.Publish(order)
+	}
+	return nil
+}
+
+// SubscribeOrders returns subscription to orders stored after the call.
+func (s Service) SubscribeOrders() *stream.Subscription {
+	return s.Hub.Subscribe(s.Config.Stream.Buffer)
+}
+
+// OrdersAfter returns page of orders matching filter and ingested after seq.
+func (s Service) OrdersAfter(ctx context.Context, seq int64, filter models.OrderFilter) ([]models.Order, error) {
+	return s.Repository.GetOrdersAfterSeq(ctx, seq, filter, s.Config.Stream.ReplayLimit)
+}
+
 func (s Service) ListOfOrders(ctx context.Context) ([]models.Order, error) {
-	m := s.Repository.Cash
-	orders := make([]models.Order, 0)
-	for _, ord := range m {
-		orders = append(orders, ord)
+	return s.Cache.All(), nil
+}
+
+// PageOfOrders returns page of orders newest first and total number of orders.
+func (s Service) PageOfOrders(ctx context.Context, page, perPage int) ([]models.Order, int, error) {
+	orders, total := s.Cache.Page((page-1)*perPage, perPage)
+	return orders, total, nil
+}
+
+// FindOrders returns page of orders matching filter newest first. Items of orders are not loaded.
+func (s Service) FindOrders(ctx context.Context, filter models.OrderFilter, limit, offset uint64) ([]repository.OrderRecord, error) {
+	return s.Repository.FindOrders(ctx, filter, limit, offset)
+}
+
+// OrderItems returns items with given ids by their ids.
+func (s Service) OrderItems(ctx context.Context, ids []int64) (map[int64]models.Item, error) {
+	return s.Repository.GetItems(ctx, ids)
+}
+
+// CustomerOrders returns page of customer orders newest first.
+func (s Service) CustomerOrders(ctx context.Context, customerId string, limit, offset uint64) ([]models.Order, error) {
+	return s.Repository.GetCustomerOrders(ctx, customerId, limit, offset)
+}
+
+// CustomerSummary returns aggregated view of customer orders.
+func (s Service) CustomerSummary(ctx context.Context, customerId string) (models.CustomerSummary, error) {
+	summary, err := s.Repository.GetCustomerSummary(ctx, customerId)
+	if err != nil {
+		return models.CustomerSummary{}, err
 	}
-	return orders, nil
+	if summary.OrdersCount == 0 {
+		return models.CustomerSummary{}, fmt.Errorf("%w: customer with id=%s has no orders", ErrCustomerNotFound, customerId)
+	}
+	return summary, nil
+}
+
+func (s Service) RevenueReport(ctx context.Context, q models.ReportQuery) ([]models.RevenueRow, error) {
+	return s.Repository.RevenueReport(ctx, q)
+}
+
+// TopItemsReport returns up to limit the most sold items or brands in every report group.
+func (s Service) TopItemsReport(ctx context.Context, q models.ReportQuery, byBrand bool, limit int) ([]models.TopItemRow, error) {
+	return s.Repository.TopItemsReport(ctx, q, byBrand, limit)
 }
+
+func (s Service) DeliveryServicesReport(ctx context.Context, q models.ReportQuery) ([]models.DeliveryServiceRow, error) {
+	return s.Repository.DeliveryServicesReport(ctx, q)
+}
+
+// ExportOrders passes all orders matching filter to handle in ingestion order, batch by batch.
+func (s Service) ExportOrders(ctx context.Context, filter models.OrderFilter, handle func([]models.Order) error) error {
+	return s.Repository.ExportOrders(ctx, filter, exportBatchSize, handle)
+}
+
+// SearchOrders returns orders delivered to given email or phone, newest first.
+func (s Service) SearchOrders(ctx context.Context, email, phone string) ([]models.Order, error) {
+	return s.Repository.GetOrdersByContact(ctx, email, phone)
+}
+
+// GetOrder returns order from cache. Orders missing from cache, since warm-up is not finished or
+// is limited, are read from storage and cached. Orders missing from storage are remembered by Misses,
+// so floods of unknown ids don't reach storage.
 func (s Service) GetOrder(ctx context.Context, orderId string) (models.Order, error) {
-	order, ok := s.Repository.Cash[orderId]
-	if !ok {
-		return models.Order{}, fmt.Errorf("order with id=%s not exsits", orderId)
+	if order, ok := s.Cache.Get(orderId); ok {
+		return order, nil
+	}
+	if s.Misses.Has(orderId) {
+		return models.Order{}, fmt.Errorf("%w: order with id=%s not exsits", ErrOrderNotFound, orderId)
 	}
+	order, err := s.Repository.GetOrderById(ctx, orderId)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if order.OrderId == "" {
+		s.Misses.Add(orderId)
+		return models.Order{}, fmt.Errorf("%w: order with id=%s not exsits", ErrOrderNotFound, orderId)
+	}
+	s.Cache.SetIfNewer(order)
 	return order, nil
 }
+
+// WarmUpCache loads stored orders into cache according to cache config.
+func (s Service) WarmUpCache(ctx context.Context) error {
+	return s.Repository.WarmUpCache(ctx, s.Config.Cache)
+}
+
+// RunCacheSnapshots periodically saves cache snapshot if it is configured.
+func (s Service) RunCacheSnapshots(ctx context.Context) error {
+	if s.Config.Cache.SnapshotFile == "" || s.Config.Cache.SnapshotInterval <= 0 {
+		return nil
+	}
+	return s.Repository.RunSnapshots(ctx, s.Config.Cache)
+}
@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"sync"
+
+	"wb-tech-backend/internal/models"
+)
+
+// Hub broadcasts stored orders to subscribers.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives orders published to Hub. Its channel is closed when subscription
+// is closed or when subscriber does not keep up with published orders.
+type Subscription struct {
+	C <-chan models.Order
+
+	ch  chan models.Order
+	hub *Hub
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe returns new subscription buffering up to buffer orders.
+func (h *Hub) Subscribe(buffer int) *Subscription {
+	ch := make(chan models.Order, buffer)
+	sub := &Subscription{C: ch, ch: ch, hub: h}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Publish sends order to all subscribers without blocking. Subscribers with full buffer are dropped.
+func (h *Hub) Publish(order models.Order) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		select {
+		case sub.ch <- order:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subscribers[s]; ok {
+		delete(s.hub.subscribers, s)
+		close(s.ch)
+	}
+}
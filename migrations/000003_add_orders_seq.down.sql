@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS orders_seq_idx;
+ALTER TABLE orders DROP COLUMN IF EXISTS seq;
+DROP SEQUENCE IF EXISTS orders_seq;
@@ -0,0 +1,4 @@
+CREATE SEQUENCE IF NOT EXISTS orders_seq;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS seq BIGINT NOT NULL DEFAULT nextval('orders_seq');
+ALTER SEQUENCE orders_seq OWNED BY orders.seq;
+CREATE UNIQUE INDEX IF NOT EXISTS orders_seq_idx ON orders (seq);
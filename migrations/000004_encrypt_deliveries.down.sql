@@ -0,0 +1,27 @@
+-- encrypted personal data would be lost, run "keys decrypt" before rollback
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM deliveries WHERE key_id IS NOT NULL) THEN
+        RAISE EXCEPTION 'deliveries contain encrypted personal data, run "keys decrypt" before rollback';
+    END IF;
+END
+$$;
+DROP INDEX IF EXISTS deliveries_email_idx;
+DROP INDEX IF EXISTS deliveries_phone_idx;
+DROP INDEX IF EXISTS deliveries_email_bidx_idx;
+DROP INDEX IF EXISTS deliveries_phone_bidx_idx;
+DROP INDEX IF EXISTS deliveries_key_id_idx;
+ALTER TABLE deliveries
+    DROP COLUMN IF EXISTS email_bidx,
+    DROP COLUMN IF EXISTS phone_bidx,
+    DROP COLUMN IF EXISTS email_enc,
+    DROP COLUMN IF EXISTS address_enc,
+    DROP COLUMN IF EXISTS phone_enc,
+    DROP COLUMN IF EXISTS name_enc,
+    DROP COLUMN IF EXISTS wrapped_key,
+    DROP COLUMN IF EXISTS key_id;
+ALTER TABLE deliveries
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN phone SET NOT NULL,
+    ALTER COLUMN address SET NOT NULL,
+    ALTER COLUMN email SET NOT NULL;
@@ -0,0 +1,18 @@
+ALTER TABLE deliveries
+    ALTER COLUMN name DROP NOT NULL,
+    ALTER COLUMN phone DROP NOT NULL,
+    ALTER COLUMN address DROP NOT NULL,
+    ALTER COLUMN email DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS key_id VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS wrapped_key BYTEA,
+    ADD COLUMN IF NOT EXISTS name_enc BYTEA,
+    ADD COLUMN IF NOT EXISTS phone_enc BYTEA,
+    ADD COLUMN IF NOT EXISTS address_enc BYTEA,
+    ADD COLUMN IF NOT EXISTS email_enc BYTEA,
+    ADD COLUMN IF NOT EXISTS phone_bidx BYTEA,
+    ADD COLUMN IF NOT EXISTS email_bidx BYTEA;
+CREATE INDEX IF NOT EXISTS deliveries_key_id_idx ON deliveries (key_id);
+CREATE INDEX IF NOT EXISTS deliveries_phone_bidx_idx ON deliveries (phone_bidx);
+CREATE INDEX IF NOT EXISTS deliveries_email_bidx_idx ON deliveries (email_bidx);
+CREATE INDEX IF NOT EXISTS deliveries_phone_idx ON deliveries (phone);
+CREATE INDEX IF NOT EXISTS deliveries_email_idx ON deliveries (email);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS orders_delivery_service_seq_idx;
+DROP INDEX IF EXISTS orders_customer_id_seq_idx;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS orders_customer_id_seq_idx ON orders (customer_id, seq DESC);
+CREATE INDEX IF NOT EXISTS orders_delivery_service_seq_idx ON orders (delivery_service, seq DESC);
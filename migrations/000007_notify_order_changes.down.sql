@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS items_notify_changed ON items;
+DROP TRIGGER IF EXISTS payments_notify_changed ON payments;
+DROP TRIGGER IF EXISTS deliveries_notify_changed ON deliveries;
+DROP TRIGGER IF EXISTS orders_notify_changed ON orders;
+DROP FUNCTION IF EXISTS notify_order_changed();
+DROP INDEX IF EXISTS orders_items_ids_idx;
+DROP INDEX IF EXISTS orders_payment_id_idx;
+DROP INDEX IF EXISTS orders_delivery_id_idx;
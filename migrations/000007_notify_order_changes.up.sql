@@ -0,0 +1,41 @@
+CREATE INDEX IF NOT EXISTS orders_delivery_id_idx ON orders (delivery_id);
+CREATE INDEX IF NOT EXISTS orders_payment_id_idx ON orders (payment_id);
+CREATE INDEX IF NOT EXISTS orders_items_ids_idx ON orders USING GIN (items_ids);
+
+-- notify_order_changed sends order_uid of orders affected by changed row to orders_changed channel.
+-- Transactions that don't change orders content (e.g. keys rotation) set app.skip_order_notify.
+CREATE OR REPLACE FUNCTION notify_order_changed() RETURNS trigger AS $$
+DECLARE
+    changed RECORD;
+BEGIN
+    IF current_setting('app.skip_order_notify', true) = 'on' THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+    IF TG_TABLE_NAME = 'orders' THEN
+        PERFORM pg_notify('orders_changed', changed.order_uid);
+    ELSIF TG_TABLE_NAME = 'deliveries' THEN
+        PERFORM pg_notify('orders_changed', o.order_uid) FROM orders o WHERE o.delivery_id = changed.delivery_id;
+    ELSIF TG_TABLE_NAME = 'payments' THEN
+        PERFORM pg_notify('orders_changed', o.order_uid) FROM orders o WHERE o.payment_id = changed.payment_id;
+    ELSIF TG_TABLE_NAME = 'items' THEN
+        PERFORM pg_notify('orders_changed', o.order_uid) FROM orders o WHERE o.items_ids @> ARRAY[changed.item_id];
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- New orders are not notified, since orders missing from cache are read from storage.
+-- Deliveries, payments and items are inserted before their orders, so only their changes are notified.
+CREATE OR REPLACE TRIGGER orders_notify_changed AFTER UPDATE OR DELETE ON orders
+    FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
+CREATE OR REPLACE TRIGGER deliveries_notify_changed AFTER UPDATE OR DELETE ON deliveries
+    FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
+CREATE OR REPLACE TRIGGER payments_notify_changed AFTER UPDATE OR DELETE ON payments
+    FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
+CREATE OR REPLACE TRIGGER items_notify_changed AFTER UPDATE OR DELETE ON items
+    FOR EACH ROW EXECUTE FUNCTION notify_order_changed();
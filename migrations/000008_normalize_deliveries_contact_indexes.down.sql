@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS deliveries_phone_norm_idx;
+DROP INDEX IF EXISTS deliveries_email_norm_idx;
+CREATE INDEX IF NOT EXISTS deliveries_phone_idx ON deliveries (phone);
+CREATE INDEX IF NOT EXISTS deliveries_email_idx ON deliveries (email);
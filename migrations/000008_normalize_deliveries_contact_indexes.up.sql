@@ -0,0 +1,5 @@
+-- plain text contacts are searched by normalized values like blind indexes of encrypted ones
+DROP INDEX IF EXISTS deliveries_email_idx;
+DROP INDEX IF EXISTS deliveries_phone_idx;
+CREATE INDEX IF NOT EXISTS deliveries_email_norm_idx ON deliveries (lower(trim(email)));
+CREATE INDEX IF NOT EXISTS deliveries_phone_norm_idx ON deliveries (regexp_replace(phone, '[^0-9]', '', 'g'));